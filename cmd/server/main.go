@@ -4,14 +4,20 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/JulianDominic/GatheringTheBulk/internal/api/backup"
 	"github.com/JulianDominic/GatheringTheBulk/internal/api/common"
+	"github.com/JulianDominic/GatheringTheBulk/internal/api/export"
 	"github.com/JulianDominic/GatheringTheBulk/internal/api/inventory"
 	"github.com/JulianDominic/GatheringTheBulk/internal/api/jobs"
 	"github.com/JulianDominic/GatheringTheBulk/internal/api/pages"
 	"github.com/JulianDominic/GatheringTheBulk/internal/api/review"
+	"github.com/JulianDominic/GatheringTheBulk/internal/api/value"
 	"github.com/JulianDominic/GatheringTheBulk/internal/database"
+	"github.com/JulianDominic/GatheringTheBulk/internal/scryfall"
 	"github.com/JulianDominic/GatheringTheBulk/internal/store"
 	"github.com/JulianDominic/GatheringTheBulk/internal/worker"
 )
@@ -32,12 +38,38 @@ func main() {
 	renderer := &common.Renderer{Store: s}
 	dispatcher := worker.NewDispatcher(s, 100)
 	dispatcher.Start(3)
+	if err := worker.RecoverJobs(s, dispatcher); err != nil {
+		log.Printf("Failed to recover interrupted jobs: %v", err)
+	}
+
+	backupDir := os.Getenv("BACKUP_DIR")
+	if backupDir == "" {
+		backupDir = "backups"
+	}
+	imageDir := os.Getenv("IMAGE_DIR")
+	if imageDir == "" {
+		imageDir = "images"
+	}
+
+	sf := scryfall.NewClient()
+	if u := os.Getenv("SCRYFALL_API_URL"); u != "" {
+		sf.BaseURL = strings.TrimRight(u, "/")
+	}
+
+	scheduler := &worker.Scheduler{Store: s, Dispatcher: dispatcher, DB: database.DB, BackupDir: backupDir}
+	scheduler.Start()
 
 	// 3. Initialize Handlers
-	pagesHandler := &pages.Handler{Store: s, Renderer: renderer}
-	inventoryHandler := &inventory.Handler{Store: s, Renderer: renderer}
+	pagesHandler := &pages.Handler{Store: s, Renderer: renderer, BackupDir: backupDir}
+	inventoryHandler := &inventory.Handler{Store: s, Renderer: renderer, ImageDir: imageDir}
 	reviewHandler := &review.Handler{Store: s, Renderer: renderer}
-	jobsHandler := &jobs.Handler{Store: s, Dispatcher: dispatcher}
+	exportHandler := &export.Handler{Store: s, Renderer: renderer}
+	valueHandler := &value.Handler{Store: s, Renderer: renderer}
+	backupHandler := &backup.Handler{DB: database.DB, Store: s, Scheduler: scheduler}
+	jobsHandler := &jobs.Handler{Store: s, Dispatcher: dispatcher, Renderer: renderer, ImageDir: imageDir, Scryfall: sf}
+	if mb, err := strconv.ParseInt(os.Getenv("MAX_UPLOAD_MB"), 10, 64); err == nil && mb > 0 {
+		jobsHandler.MaxUploadBytes = mb << 20
+	}
 
 	// 4. Setup Routes
 	mux := http.NewServeMux()
@@ -46,22 +78,51 @@ func main() {
 	// Pages
 	mux.HandleFunc("GET /", pagesHandler.HandleDashboard)
 	mux.HandleFunc("GET /settings", pagesHandler.HandleSettings)
+	mux.HandleFunc("POST /settings/sync", pagesHandler.HandleSaveSync)
+	mux.HandleFunc("POST /settings/matching", pagesHandler.HandleSaveMatching)
+	mux.HandleFunc("POST /settings/backup", pagesHandler.HandleSaveBackup)
 	mux.HandleFunc("GET /import", pagesHandler.HandleImportHub)
+	mux.HandleFunc("GET /import/preview/{id}", pagesHandler.HandleImportPreview)
 
 	// API / HTMX
 	mux.HandleFunc("GET /api/jobs/{id}", jobsHandler.HandleStatus)
 	mux.HandleFunc("POST /api/jobs/sync", jobsHandler.HandleSync)
+	mux.HandleFunc("POST /api/jobs/sync-file", jobsHandler.HandleSyncFile)
+	mux.HandleFunc("POST /api/jobs/images", jobsHandler.HandleCacheImages)
 	mux.HandleFunc("POST /api/jobs/import", jobsHandler.HandleImport)
+	mux.HandleFunc("POST /api/jobs/import-text", jobsHandler.HandleImportText)
+	mux.HandleFunc("POST /api/jobs/import/{id}/mapping", jobsHandler.HandleImportMapping)
+	mux.HandleFunc("DELETE /api/jobs/import/{id}/mapping", jobsHandler.HandleCancelMapping)
+	mux.HandleFunc("DELETE /api/import-profiles/{id}", jobsHandler.HandleDeleteProfile)
+	mux.HandleFunc("POST /api/jobs/{id}/commit", jobsHandler.HandleCommitPreview)
+	mux.HandleFunc("POST /api/jobs/{id}/discard", jobsHandler.HandleDiscardPreview)
+	mux.HandleFunc("POST /api/jobs/{id}/revert", jobsHandler.HandleRevert)
 	mux.HandleFunc("GET /api/search", inventoryHandler.HandleSearch)
 	mux.HandleFunc("GET /api/inventory/autocomplete", inventoryHandler.HandleAutocomplete)
+	mux.HandleFunc("GET /sets/{code}/icon.svg", inventoryHandler.HandleSetIcon)
+	mux.HandleFunc("GET /images/{id}", inventoryHandler.HandleImage)
 
 	// Inventory
 	mux.HandleFunc("GET /inventory/edit/{id}", inventoryHandler.HandleEditModal)
+	mux.HandleFunc("GET /inventory/group/{oracle_id}", inventoryHandler.HandleStacks)
 	mux.HandleFunc("GET /inventory/add-details/{scryfall_id}", inventoryHandler.HandleAddDetails)
 	mux.HandleFunc("POST /inventory", inventoryHandler.HandleAdd)
 	mux.HandleFunc("PUT /inventory/{id}", inventoryHandler.HandleEdit)
 	mux.HandleFunc("DELETE /inventory/{id}", inventoryHandler.HandleDelete)
 
+	// Export
+	mux.HandleFunc("GET /export", exportHandler.HandlePage)
+	mux.HandleFunc("GET /export/download", exportHandler.HandleDownload)
+	mux.HandleFunc("GET /export/deck", exportHandler.HandleDeck)
+
+	// Value
+	mux.HandleFunc("GET /value", valueHandler.HandlePage)
+
+	// Backup
+	mux.HandleFunc("GET /api/backup", backupHandler.HandleDownload)
+	mux.HandleFunc("POST /api/backup/run", backupHandler.HandleRun)
+	mux.HandleFunc("POST /api/backup/restore", backupHandler.HandleRestore)
+
 	// Review
 	mux.HandleFunc("GET /review/content", reviewHandler.HandleContent)
 	mux.HandleFunc("GET /review/resolve/{id}", reviewHandler.HandleResolveModal)
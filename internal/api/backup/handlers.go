@@ -0,0 +1,149 @@
+package backup
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/database"
+	"github.com/JulianDominic/GatheringTheBulk/internal/store"
+	"github.com/JulianDominic/GatheringTheBulk/internal/worker"
+)
+
+// maxRestoreBytes bounds an uploaded backup; the card database alone is a
+// few hundred megabytes.
+const maxRestoreBytes = 4 << 30
+
+type Handler struct {
+	DB        *sql.DB
+	Store     store.Store
+	Scheduler *worker.Scheduler
+}
+
+// HandleDownload streams a consistent snapshot of the database.
+func (h *Handler) HandleDownload(w http.ResponseWriter, r *http.Request) {
+	dir, err := os.MkdirTemp("", "gtb-backup-")
+	if err != nil {
+		log.Printf("Failed to create backup dir: %v", err)
+		http.Error(w, "Internal Error", http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "backup.db")
+	if err := database.Backup(h.DB, path); err != nil {
+		log.Printf("Backup failed: %v", err)
+		http.Error(w, "Backup failed", http.StatusInternalServerError)
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Printf("Failed to open backup: %v", err)
+		http.Error(w, "Internal Error", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	filename := fmt.Sprintf("gatheringthebulk-%s.db", time.Now().Format("2006-01-02-150405"))
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	if info, err := f.Stat(); err == nil {
+		w.Header().Set("Content-Length", fmt.Sprint(info.Size()))
+	}
+	if _, err := io.Copy(w, f); err != nil {
+		log.Printf("Failed to send backup: %v", err)
+	}
+}
+
+// HandleRun queues a backup to the backup directory right away.
+func (h *Handler) HandleRun(w http.ResponseWriter, r *http.Request) {
+	job, err := h.Scheduler.QueueBackup()
+	if err != nil {
+		log.Printf("Failed to queue backup: %v", err)
+		http.Error(w, "DB Error", http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Fprintf(w, `<div hx-get="/api/jobs/%s" hx-trigger="load delay:500ms, every 1s" hx-swap="outerHTML">
+		<p aria-busy="true">Backing up...</p>
+	</div>`, job.ID)
+}
+
+// HandleRestore replaces the database with an uploaded backup once it has
+// been checked to be an intact GatheringTheBulk database.
+func (h *Handler) HandleRestore(w http.ResponseWriter, r *http.Request) {
+	// Jobs write as they go, so they must not run across a restore. Holding
+	// the dispatcher also keeps the scheduler from queueing any meanwhile.
+	resume, ok := h.Scheduler.Dispatcher.Pause()
+	if !ok {
+		restoreError(w, http.StatusConflict, "Wait for running imports and syncs to finish before restoring.")
+		return
+	}
+	defer resume()
+	if jobs, err := h.Store.ListUnfinishedJobs(); err != nil || len(jobs) > 0 {
+		restoreError(w, http.StatusConflict, "Wait for running imports and syncs to finish before restoring.")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRestoreBytes)
+	file, _, err := r.FormFile("backup_file")
+	if err != nil {
+		restoreError(w, http.StatusBadRequest, "Please choose a backup file.")
+		return
+	}
+	defer file.Close()
+
+	dir, err := os.MkdirTemp("", "gtb-restore-")
+	if err != nil {
+		log.Printf("Failed to create restore dir: %v", err)
+		restoreError(w, http.StatusInternalServerError, "The upload could not be saved.")
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "restore.db")
+	dst, err := os.Create(path)
+	if err != nil {
+		log.Printf("Failed to create restore file: %v", err)
+		restoreError(w, http.StatusInternalServerError, "The upload could not be saved.")
+		return
+	}
+	_, err = io.Copy(dst, file)
+	dst.Close()
+	if err != nil {
+		log.Printf("Failed to save restore file: %v", err)
+		restoreError(w, http.StatusInternalServerError, "The upload could not be saved.")
+		return
+	}
+
+	if err := database.ValidateBackup(path); err != nil {
+		restoreError(w, http.StatusBadRequest, "This is not a usable backup: "+err.Error()+".")
+		return
+	}
+
+	if err := database.Restore(h.DB, path); err != nil {
+		log.Printf("Restore failed: %v", err)
+		restoreError(w, http.StatusInternalServerError, "Restoring failed, the current database was kept: "+err.Error())
+		return
+	}
+
+	log.Printf("Database restored from uploaded backup")
+	w.Header().Set("HX-Trigger", "review-count-updated")
+	fmt.Fprint(w, `<div style="background-color:#2e7d32; color:white; padding:1rem; border-radius:4px; margin-top:1rem;">
+		<strong>Backup Restored!</strong>
+		<p style="margin-bottom:0; margin-top:0.5rem;">Your collection now matches the backup.</p>
+	</div>`)
+}
+
+func restoreError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<div class="pico-color-red"><strong>Restore failed:</strong> %s</div>`, html.EscapeString(msg))
+}
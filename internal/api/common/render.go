@@ -17,6 +17,7 @@ func (r *Renderer) Render(w http.ResponseWriter, req *http.Request, tmplName str
 	funcMap := template.FuncMap{
 		"add": func(a, b int) int { return a + b },
 		"sub": func(a, b int) int { return a - b },
+		"neg": func(f float64) float64 { return -f },
 	}
 
 	files := []string{
@@ -53,6 +54,7 @@ func (r *Renderer) RenderPartial(w http.ResponseWriter, tmplName string, data in
 	funcMap := template.FuncMap{
 		"add": func(a, b int) int { return a + b },
 		"sub": func(a, b int) int { return a - b },
+		"neg": func(f float64) float64 { return -f },
 	}
 
 	tmpl, err := template.New(filepath.Base(tmplName)).Funcs(funcMap).ParseFiles(filepath.Join("web/templates", tmplName))
@@ -0,0 +1,94 @@
+package export
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/api/common"
+	"github.com/JulianDominic/GatheringTheBulk/internal/export"
+	"github.com/JulianDominic/GatheringTheBulk/internal/store"
+)
+
+type Handler struct {
+	Store    store.Store
+	Renderer *common.Renderer
+}
+
+// HandlePage shows the export options, carrying over the dashboard search.
+func (h *Handler) HandlePage(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Formats     []*export.Format
+		DeckFormats []*export.DeckFormat
+		Query       string
+	}{
+		Formats:     export.Formats,
+		DeckFormats: export.DeckFormats,
+		Query:       r.URL.Query().Get("q"),
+	}
+
+	h.Renderer.Render(w, r, "export.html", data)
+}
+
+// HandleDownload streams the inventory, or the cards matching a search, as a
+// CSV in the chosen format.
+func (h *Handler) HandleDownload(w http.ResponseWriter, r *http.Request) {
+	format := export.Formats[0]
+	if key := r.URL.Query().Get("format"); key != "" {
+		var ok bool
+		if format, ok = export.Lookup(key); !ok {
+			http.Error(w, "Unknown export format", http.StatusBadRequest)
+			return
+		}
+	}
+
+	filename := fmt.Sprintf("gatheringthebulk-%s-%s.csv", format.Key, time.Now().Format("2006-01-02"))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	// Headers are already sent once rows stream, so a failure can only be logged
+	if err := export.Write(w, format, h.Store, r.URL.Query().Get("q")); err != nil {
+		log.Printf("%s export failed: %v", format.Name, err)
+	}
+}
+
+// HandleDeck exports the selected inventory rows, or the cards matching a
+// search, as a decklist with quantities summed per printing.
+func (h *Handler) HandleDeck(w http.ResponseWriter, r *http.Request) {
+	format, ok := export.LookupDeck(r.URL.Query().Get("format"))
+	if !ok {
+		http.Error(w, "Unknown decklist format", http.StatusBadRequest)
+		return
+	}
+
+	var ids []int
+	for _, v := range r.URL.Query()["id"] {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	cards, err := h.Store.ListDeckCards(ids, r.URL.Query().Get("q"))
+	if err != nil {
+		log.Printf("Failed to list deck cards: %v", err)
+		http.Error(w, "DB Error", http.StatusInternalServerError)
+		return
+	}
+	if len(cards) == 0 {
+		http.Error(w, "No cards to export", http.StatusNotFound)
+		return
+	}
+
+	filename := fmt.Sprintf("gatheringthebulk-deck-%s.%s", time.Now().Format("2006-01-02"), format.Extension)
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	if err := format.Write(w, cards); err != nil {
+		log.Printf("%s export failed: %v", format.Name, err)
+	}
+}
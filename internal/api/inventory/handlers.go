@@ -2,18 +2,23 @@ package inventory
 
 import (
 	"fmt"
+	"html"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 
 	"github.com/JulianDominic/GatheringTheBulk/internal/api/common"
 	"github.com/JulianDominic/GatheringTheBulk/internal/models"
 	"github.com/JulianDominic/GatheringTheBulk/internal/store"
+	"github.com/JulianDominic/GatheringTheBulk/internal/worker"
 )
 
 type Handler struct {
 	Store    store.Store
 	Renderer *common.Renderer
+	ImageDir string // Where card images are cached
 }
 
 func (h *Handler) HandleAdd(w http.ResponseWriter, r *http.Request) {
@@ -63,13 +68,16 @@ func (h *Handler) HandleEdit(w http.ResponseWriter, r *http.Request) {
 		qty = 1
 	}
 
+	price, _ := strconv.ParseFloat(r.FormValue("purchase_price"), 64)
+
 	item := models.InventoryItem{
-		ID:        id,
-		Quantity:  qty,
-		Condition: r.FormValue("condition"),
-		IsFoil:    r.FormValue("is_foil") == "on",
-		Language:  r.FormValue("language"),
-		Location:  "Binder",
+		ID:            id,
+		Quantity:      qty,
+		Condition:     r.FormValue("condition"),
+		IsFoil:        r.FormValue("is_foil") == "on",
+		Language:      r.FormValue("language"),
+		Location:      "Binder",
+		PurchasePrice: price,
 	}
 
 	if loc := r.FormValue("location"); loc != "" {
@@ -103,6 +111,18 @@ func (h *Handler) HandleDelete(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// HandleStacks lists the inventory rows behind one card of the dashboard's
+// grouped view, limited to the dashboard search.
+func (h *Handler) HandleStacks(w http.ResponseWriter, r *http.Request) {
+	items, err := h.Store.ListInventoryStacks(r.PathValue("oracle_id"), r.URL.Query().Get("q"))
+	if err != nil {
+		log.Printf("Error listing stacks: %v", err)
+		http.Error(w, "DB Error", http.StatusInternalServerError)
+		return
+	}
+	h.Renderer.RenderPartial(w, "partials/inventory_stacks.html", items)
+}
+
 func (h *Handler) HandleEditModal(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, _ := strconv.Atoi(idStr)
@@ -141,15 +161,63 @@ func (h *Handler) HandleAutocomplete(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// placeholderImage is shown for cards with no image at all.
+const placeholderImage = "/static/img/card-placeholder.svg"
+
+// HandleImage serves a card image from the local cache. Uncached images are
+// redirected to Scryfall; pages fall back to the placeholder if that fails
+// too, as it does offline.
+func (h *Handler) HandleImage(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" || strings.ContainsAny(id, `./\`) {
+		http.NotFound(w, r)
+		return
+	}
+
+	path := worker.ImagePath(h.ImageDir, id)
+	if _, err := os.Stat(path); err == nil {
+		w.Header().Set("Cache-Control", "public, max-age=604800")
+		http.ServeFile(w, r, path)
+		return
+	}
+
+	card, err := h.Store.GetCardByScryfallID(id)
+	if err != nil || card.ImageURI == "" {
+		http.Redirect(w, r, placeholderImage, http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, card.ImageURI, http.StatusFound)
+}
+
+// placeholderIcon stands in for sets whose icon has not been downloaded.
+const placeholderIcon = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 32 32"><circle cx="16" cy="16" r="12" fill="none" stroke="currentColor" stroke-width="3"/></svg>`
+
+// HandleSetIcon serves a set's icon from the local cache.
+func (h *Handler) HandleSetIcon(w http.ResponseWriter, r *http.Request) {
+	svg, err := h.Store.GetSetIcon(r.PathValue("code"))
+	if err != nil {
+		log.Printf("Failed to load set icon: %v", err)
+	}
+	if svg == "" {
+		svg = placeholderIcon
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	fmt.Fprint(w, svg)
+}
+
 func (h *Handler) HandleSearch(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query().Get("q")
 	set := r.URL.Query().Get("set")
+	lang := models.NormalizeLanguage(r.URL.Query().Get("lang"))
 
 	if len(q) < 2 {
 		return
 	}
 
-	results, err := h.Store.SearchCards(q, set)
+	results, err := h.Store.SearchCards(q, set, lang)
 	if err != nil {
 		log.Printf("Search error: %v", err)
 		http.Error(w, "Search failed", http.StatusInternalServerError)
@@ -167,6 +235,12 @@ func (h *Handler) HandleSearch(w http.ResponseWriter, r *http.Request) {
 			hxAttr = fmt.Sprintf(`hx-get="/inventory/add-details/%s" hx-target="#selected-card-container"`, c.ScryfallID)
 		}
 
+		// Foreign printings are marked with their language
+		var langTag string
+		if c.Lang != "" && c.Lang != "en" {
+			langTag = " · " + strings.ToUpper(c.Lang)
+		}
+
 		fmt.Fprintf(w, `
 			<li role="option" 
 				class="search-result-item"
@@ -174,12 +248,17 @@ func (h *Handler) HandleSearch(w http.ResponseWriter, r *http.Request) {
 				%s
 				hx-trigger="click"
 				hx-on:click="document.getElementById('res-confirmation').style.display='block'">
-				<img src="%s" style="height:40px; border-radius:4px; flex-shrink:0;">
+				<img src="/images/%s" style="height:40px; border-radius:4px; flex-shrink:0;"
+					onerror="this.onerror=null; this.src='/static/img/card-placeholder.svg';">
 				<div style="flex-grow:1; overflow:hidden;">
 					<strong style="color:var(--text-primary); display:block; white-space:nowrap; overflow:hidden; text-overflow:ellipsis;">%s</strong>
-					<small style="display:block; color:var(--text-secondary); text-transform: uppercase;">%s #%s</small>
+					<small style="display:flex; gap:0.35rem; align-items:center; color:var(--text-secondary);">
+						<img src="/sets/%s/icon.svg" alt="" class="set-icon">
+						<span style="white-space:nowrap; overflow:hidden; text-overflow:ellipsis;">%s</span>
+						<span style="text-transform: uppercase; white-space:nowrap;">%s #%s%s</span>
+					</small>
 				</div>
 			</li>`,
-			hxAttr, c.ImageURI, c.Name, c.SetCode, c.CollectorNumber)
+			hxAttr, c.ScryfallID, c.Name, c.SetCode, html.EscapeString(c.SetName), c.SetCode, c.CollectorNumber, langTag)
 	}
 }
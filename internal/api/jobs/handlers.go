@@ -2,15 +2,22 @@ package jobs
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/JulianDominic/GatheringTheBulk/internal/api/common"
 	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+	"github.com/JulianDominic/GatheringTheBulk/internal/scryfall"
 	"github.com/JulianDominic/GatheringTheBulk/internal/store"
 	"github.com/JulianDominic/GatheringTheBulk/internal/worker"
 	"github.com/google/uuid"
@@ -19,6 +26,11 @@ import (
 type Handler struct {
 	Store      store.Store
 	Dispatcher *worker.Dispatcher
+	Renderer   *common.Renderer
+
+	MaxUploadBytes int64            // Largest accepted CSV upload, DefaultMaxUploadBytes if zero
+	ImageDir       string           // Where card images are cached
+	Scryfall       *scryfall.Client // Used by sync and image jobs
 }
 
 func (h *Handler) HandleStatus(w http.ResponseWriter, r *http.Request) {
@@ -46,21 +58,50 @@ func (h *Handler) HandleStatus(w http.ResponseWriter, r *http.Request) {
 						<p style="margin-bottom:0; margin-top:0.5rem;">%s</p>
 					</div>`, job.ResultSummary)
 				triggers = "document.body.dispatchEvent(new CustomEvent('scryfall-synced'));"
+			} else if job.Type == models.JobTypeImageCache {
+				completionHTML = fmt.Sprintf(`
+					<div style="background-color:#2e7d32; color:white; padding:1rem; border-radius:4px; margin-top:1rem;">
+						<strong>Images Cached!</strong>
+						<p style="margin-bottom:0; margin-top:0.5rem;">%s</p>
+					</div>`, html.EscapeString(job.ResultSummary))
+			} else if job.Type == models.JobTypeBackup {
+				completionHTML = fmt.Sprintf(`
+					<div style="background-color:#2e7d32; color:white; padding:1rem; border-radius:4px; margin-top:1rem;">
+						<strong>Backup Complete!</strong>
+						<p style="margin-bottom:0; margin-top:0.5rem;">%s</p>
+					</div>`, html.EscapeString(job.ResultSummary))
 			} else {
 				var res struct {
-					Success int `json:"success"`
-					Review  int `json:"review"`
+					Success int    `json:"success"`
+					Review  int    `json:"review"`
+					Format  string `json:"format"`
+					Preview bool   `json:"preview"`
 				}
 				json.Unmarshal([]byte(job.ResultSummary), &res)
 
+				if res.Preview {
+					fmt.Fprintf(w, `
+						<div style="background:var(--surface-color); border:1px solid var(--border-color); padding:1rem; border-radius:4px; margin-top:1rem;">
+							<strong>Preview Ready</strong>
+							<ul style="margin-top:0.5rem;">
+								<li>Detected Format: <strong>%s</strong></li>
+								<li>Would Be Added: <strong>%d</strong></li>
+								<li>Would Need Review: <strong>%d</strong></li>
+							</ul>
+							<a href="/import/preview/%s" role="button">Review Preview</a>
+						</div>`, html.EscapeString(res.Format), res.Success, res.Review, job.ID)
+					return
+				}
+
 				completionHTML = fmt.Sprintf(`
 					<div style="background-color:#2e7d32; color:white; padding:1rem; border-radius:4px; margin-top:1rem;">
 						<strong>Import Complete!</strong>
 						<ul style="margin-bottom:0; margin-top:0.5rem;">
+							<li>Detected Format: <strong>%s</strong></li>
 							<li>Successfully Added: <strong>%d</strong></li>
 							<li>Sent to Review: <strong>%d</strong></li>
 						</ul>
-					</div>`, res.Success, res.Review)
+					</div>`, html.EscapeString(res.Format), res.Success, res.Review)
 				triggers = `
 					document.body.dispatchEvent(new CustomEvent('review-count-updated'));
 					const reviewLoader = document.querySelector('#review-tab > div');
@@ -76,6 +117,8 @@ func (h *Handler) HandleStatus(w http.ResponseWriter, r *http.Request) {
 					})();
 				</script>
 			`, completionHTML, triggers)))
+		} else if job.Status == models.JobStatusDiscarded {
+			fmt.Fprint(w, `<div><strong>Discarded:</strong> the import preview was not applied.</div>`)
 		} else if job.Status == models.JobStatusFailed {
 			fmt.Fprintf(w, `<div class="pico-color-red"><strong>Failed:</strong> %s</div>`, job.ResultSummary)
 		} else {
@@ -91,6 +134,11 @@ func (h *Handler) HandleStatus(w http.ResponseWriter, r *http.Request) {
 							<small style="color:var(--text-secondary);">Processed %d cards so far. This may take a few minutes.</small>
 						</div>
 					</div>`, job.ProgressCurrent)
+			} else if job.Type == models.JobTypeBackup {
+				content = `<p aria-busy="true">Backing up...</p>`
+			} else if job.Type == models.JobTypeImageCache {
+				content = fmt.Sprintf(`<p>Downloading card images... %d of %d checked</p><progress value="%d" max="%d"></progress>`,
+					job.ProgressCurrent, job.ProgressTotal, job.ProgressCurrent, job.ProgressTotal)
 			}
 
 			fmt.Fprintf(w, `<div hx-get="/api/jobs/%s" hx-trigger="every 1s" hx-swap="outerHTML">
@@ -104,22 +152,110 @@ func (h *Handler) HandleStatus(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) HandleSync(w http.ResponseWriter, r *http.Request) {
+	h.queueSync(w, uuid.New().String(), models.SyncOptions{Force: r.FormValue("force") == "on"},
+		"Connecting to Scryfall API")
+}
+
+// maxBulkUploadBytes bounds an uploaded bulk data file. Scryfall's all_cards
+// file is well over a gigabyte uncompressed.
+const maxBulkUploadBytes = 4 << 30
+
+// HandleSyncFile syncs the card database from a Scryfall bulk data file,
+// either uploaded as "bulk_file" or read from the server's disk at "path".
+// The file may be gzip-compressed.
+func (h *Handler) HandleSyncFile(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBulkUploadBytes)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		uploadError(w, http.StatusBadRequest, "The upload was not a file form. Please choose a bulk data file and try again.")
+		return
+	}
+
 	jobID := uuid.New().String()
+
+	if _, err := os.Stat("uploads"); os.IsNotExist(err) {
+		os.Mkdir("uploads", 0755)
+	}
+	dstPath := filepath.Join("uploads", jobID+".json")
+
+	var path string
+	gotFile := false
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			os.Remove(dstPath)
+			uploadReadError(w, err, maxBulkUploadBytes)
+			return
+		}
+
+		if part.FormName() != "bulk_file" {
+			value, err := io.ReadAll(io.LimitReader(part, maxFieldBytes))
+			if err != nil {
+				os.Remove(dstPath)
+				uploadReadError(w, err, maxBulkUploadBytes)
+				return
+			}
+			if part.FormName() == "path" {
+				path = strings.TrimSpace(string(value))
+			}
+			continue
+		}
+
+		if gotFile || part.FileName() == "" {
+			continue
+		}
+		if err := saveUpload(dstPath, part); err != nil {
+			os.Remove(dstPath)
+			uploadReadError(w, err, maxBulkUploadBytes)
+			return
+		}
+		gotFile = true
+	}
+
+	opts, detail := models.SyncOptions{Path: dstPath, Uploaded: true}, "Reading the uploaded file"
+	if !gotFile {
+		if path == "" {
+			uploadError(w, http.StatusBadRequest, "Choose a bulk data file or enter its path on the server.")
+			return
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			uploadError(w, http.StatusBadRequest, fmt.Sprintf("No file was found at %s on the server.", path))
+			return
+		}
+		opts, detail = models.SyncOptions{Path: filepath.Clean(path)}, "Reading "+filepath.Base(path)
+	}
+
+	h.queueSync(w, jobID, opts, detail)
+}
+
+// queueSync creates a sync job and hands it to the dispatcher, responding
+// with the progress poller.
+func (h *Handler) queueSync(w http.ResponseWriter, jobID string, opts models.SyncOptions, detail string) {
+	params, _ := json.Marshal(opts)
 	job := &models.Job{
 		ID:        jobID,
 		Type:      models.JobTypeSyncDB,
 		Status:    models.JobStatusPending,
 		CreatedAt: time.Now(),
+		Params:    string(params),
 	}
 
 	if err := h.Store.CreateJob(job); err != nil {
+		if opts.Uploaded {
+			os.Remove(opts.Path)
+		}
 		http.Error(w, "DB Error", http.StatusInternalServerError)
 		return
 	}
 
 	h.Dispatcher.QueueJob(worker.JobRequest{
 		Job:     job,
-		Handler: worker.SyncDatabaseTask,
+		Handler: worker.SyncDatabaseTask(h.Scryfall),
 	})
 
 	fmt.Fprintf(w, `<div hx-get="/api/jobs/%s" hx-trigger="load delay:500ms, every 1s" hx-swap="outerHTML">
@@ -127,63 +263,237 @@ func (h *Handler) HandleSync(w http.ResponseWriter, r *http.Request) {
 			<div aria-busy="true"></div>
 			<div>
 				<strong style="display:block;">Initializing Sync...</strong>
-				<small style="color:var(--text-secondary);">Connecting to Scryfall API</small>
+				<small style="color:var(--text-secondary);">%s</small>
 			</div>
 		</div>
-    </div>`, jobID)
+    </div>`, jobID, html.EscapeString(detail))
 }
 
-func (h *Handler) HandleImport(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		http.Error(w, "File too large", http.StatusBadRequest)
+// HandleCacheImages queues a download of the images of owned cards, or of
+// every card when "all" is set.
+func (h *Handler) HandleCacheImages(w http.ResponseWriter, r *http.Request) {
+	all := r.FormValue("all") == "on"
+	params, _ := json.Marshal(map[string]bool{"all": all})
+
+	job := &models.Job{
+		ID:        uuid.New().String(),
+		Type:      models.JobTypeImageCache,
+		Status:    models.JobStatusPending,
+		CreatedAt: time.Now(),
+		Params:    string(params),
+	}
+	if err := h.Store.CreateJob(job); err != nil {
+		http.Error(w, "DB Error", http.StatusInternalServerError)
 		return
 	}
 
-	file, _, err := r.FormFile("csv_file")
+	h.Dispatcher.QueueJob(worker.JobRequest{
+		Job:     job,
+		Handler: worker.CacheImagesTask(h.Scryfall, h.ImageDir, all),
+	})
+
+	fmt.Fprintf(w, `<div hx-get="/api/jobs/%s" hx-trigger="load delay:500ms, every 1s" hx-swap="outerHTML">
+		<p aria-busy="true">Preparing image download...</p>
+	</div>`, job.ID)
+}
+
+// DefaultMaxUploadBytes is the upload limit used when MaxUploadBytes is unset.
+const DefaultMaxUploadBytes = 100 << 20
+
+// HandleImport streams an uploaded CSV straight to the uploads directory, so
+// large collection exports are never held in memory, then queues the import.
+func (h *Handler) HandleImport(w http.ResponseWriter, r *http.Request) {
+	limit := h.MaxUploadBytes
+	if limit <= 0 {
+		limit = DefaultMaxUploadBytes
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+
+	mr, err := r.MultipartReader()
 	if err != nil {
-		http.Error(w, "Invalid file", http.StatusBadRequest)
+		uploadError(w, http.StatusBadRequest, "The upload was not a file form. Please choose a CSV file and try again.")
 		return
 	}
-	defer file.Close()
 
 	jobID := uuid.New().String()
 
 	if _, err := os.Stat("uploads"); os.IsNotExist(err) {
 		os.Mkdir("uploads", 0755)
 	}
-
 	dstPath := filepath.Join("uploads", jobID+".csv")
-	dst, err := os.Create(dstPath)
-	if err != nil {
-		log.Printf("Failed to create upload file: %v", err)
+
+	// Form fields may arrive before or after the file, so collect them as
+	// the parts are read and expose them through r.Form afterwards.
+	form := url.Values{}
+	gotFile := false
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			os.Remove(dstPath)
+			uploadReadError(w, err, limit)
+			return
+		}
+
+		if part.FormName() != "csv_file" {
+			value, err := io.ReadAll(io.LimitReader(part, maxFieldBytes))
+			if err != nil {
+				os.Remove(dstPath)
+				uploadReadError(w, err, limit)
+				return
+			}
+			form.Add(part.FormName(), string(value))
+			continue
+		}
+
+		if gotFile || part.FileName() == "" {
+			continue
+		}
+		if err := saveUpload(dstPath, part); err != nil {
+			os.Remove(dstPath)
+			uploadReadError(w, err, limit)
+			return
+		}
+		gotFile = true
+	}
+
+	if !gotFile {
+		uploadError(w, http.StatusBadRequest, "No file was uploaded. Please choose a CSV file.")
+		return
+	}
+	r.Form = form
+
+	opts := importOptions(r)
+
+	// A chosen profile skips detection entirely. Otherwise ask the user to map
+	// the columns when detection can't identify cards (or they asked to),
+	// unless a saved profile fits the header row.
+	if profileID, _ := strconv.Atoi(r.FormValue("profile_id")); profileID > 0 {
+		profile, err := h.Store.GetImportProfile(profileID)
+		if err != nil {
+			os.Remove(dstPath)
+			uploadError(w, http.StatusBadRequest, "The selected column profile no longer exists.")
+			return
+		}
+		opts.Mapping, opts.Profile = profile.Mapping, profile.Name
+	} else {
+		ins, err := worker.InspectCSV(dstPath, 5)
+		if err != nil {
+			os.Remove(dstPath)
+			uploadError(w, http.StatusBadRequest, "Could not read a header row from this file. Is it a CSV export?")
+			return
+		}
+
+		manual := r.FormValue("map_columns") == "on"
+		if manual || !ins.Usable {
+			profiles, _ := h.Store.ListImportProfiles()
+			profile := worker.MatchProfile(profiles, ins.Header)
+			if manual || profile == nil {
+				h.renderMapping(w, jobID, ins, opts, "")
+				return
+			}
+			opts.Mapping, opts.Profile = profile.Mapping, profile.Name
+		}
+	}
+
+	h.queueImport(w, jobID, models.JobTypeCSVImport, opts, dstPath)
+}
+
+func (h *Handler) HandleImportText(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	decklist := strings.TrimSpace(r.FormValue("decklist"))
+	if decklist == "" {
+		http.Error(w, "Decklist is empty", http.StatusBadRequest)
+		return
+	}
+
+	jobID := uuid.New().String()
+
+	if _, err := os.Stat("uploads"); os.IsNotExist(err) {
+		os.Mkdir("uploads", 0755)
+	}
+
+	dstPath := filepath.Join("uploads", jobID+".txt")
+	if err := os.WriteFile(dstPath, []byte(decklist), 0644); err != nil {
+		log.Printf("Failed to save decklist: %v", err)
 		http.Error(w, "Internal Error", http.StatusInternalServerError)
 		return
 	}
 
-	if _, err := io.Copy(dst, file); err != nil {
+	h.queueImport(w, jobID, models.JobTypeTextImport, importOptions(r), dstPath)
+}
+
+// maxFieldBytes bounds the plain form fields sent alongside an upload.
+const maxFieldBytes = 64 << 10
+
+// saveUpload copies an uploaded file part to disk.
+func saveUpload(path string, src io.Reader) error {
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
 		dst.Close()
-		log.Printf("Failed to save file: %v", err)
-		http.Error(w, "Internal Error", http.StatusInternalServerError)
+		return err
+	}
+	return dst.Close()
+}
+
+// uploadReadError reports a failed upload, telling the user the limit if the
+// file was too large.
+func uploadReadError(w http.ResponseWriter, err error, limit int64) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		uploadError(w, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("The file is larger than the %d MB upload limit.", limit>>20))
 		return
 	}
-	dst.Close()
+	log.Printf("Failed to save upload: %v", err)
+	uploadError(w, http.StatusInternalServerError, "The upload could not be saved. Please try again.")
+}
 
+// uploadError responds with an error message the import form shows in place
+// of the job status.
+func uploadError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<div class="pico-color-red"><strong>Upload failed:</strong> %s</div>`, html.EscapeString(msg))
+}
+
+// queueImport creates the job for an uploaded file and hands it to the
+// dispatcher, responding with the progress poller.
+func (h *Handler) queueImport(w http.ResponseWriter, jobID string, jobType models.JobType, opts models.ImportOptions, path string) {
+	params, _ := json.Marshal(opts)
 	job := &models.Job{
 		ID:        jobID,
-		Type:      models.JobTypeCSVImport,
+		Type:      jobType,
 		Status:    models.JobStatusPending,
 		CreatedAt: time.Now(),
+		Params:    string(params),
 	}
 
 	if err := h.Store.CreateJob(job); err != nil {
-		os.Remove(dstPath)
+		// The file belongs to whichever job already has this ID
+		if h.uploadUnclaimed(jobID) {
+			os.Remove(path)
+		}
 		http.Error(w, "DB Error", http.StatusInternalServerError)
 		return
 	}
 
+	task := worker.ImportCSVTask
+	if jobType == models.JobTypeTextImport {
+		task = worker.ImportTextTask
+	}
 	h.Dispatcher.QueueJob(worker.JobRequest{
 		Job:     job,
-		Handler: worker.ImportCSVTask,
+		Handler: task,
 	})
 
 	fmt.Fprintf(w, `<div hx-get="/api/jobs/%s" hx-trigger="load delay:500ms, every 1s" hx-swap="outerHTML">
@@ -191,3 +501,125 @@ func (h *Handler) HandleImport(w http.ResponseWriter, r *http.Request) {
         <progress></progress>
     </div>`, jobID)
 }
+
+// HandleCommitPreview applies a dry-run import that the user confirmed.
+func (h *Handler) HandleCommitPreview(w http.ResponseWriter, r *http.Request) {
+	job, err := h.previewJob(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	// A preview is ready while its job is completed; only the first request
+	// to claim it gets to apply it.
+	claimed, err := h.Store.SwapJobStatus(job.ID, models.JobStatusCompleted, models.JobStatusPending)
+	if err != nil {
+		http.Error(w, "DB Error", http.StatusInternalServerError)
+		return
+	}
+	if !claimed {
+		http.Error(w, "This preview is already being imported", http.StatusConflict)
+		return
+	}
+
+	h.Dispatcher.QueueJob(worker.JobRequest{
+		Job:     job,
+		Handler: worker.CommitPreviewTask,
+	})
+
+	fmt.Fprintf(w, `<div hx-get="/api/jobs/%s" hx-trigger="load delay:500ms, every 1s" hx-swap="outerHTML">
+        <p>Importing...</p>
+        <progress></progress>
+    </div>`, job.ID)
+}
+
+// HandleDiscardPreview throws away a dry-run import without touching inventory.
+func (h *Handler) HandleDiscardPreview(w http.ResponseWriter, r *http.Request) {
+	job, err := h.previewJob(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	claimed, err := h.Store.SwapJobStatus(job.ID, models.JobStatusCompleted, models.JobStatusDiscarded)
+	if err != nil {
+		log.Printf("Failed to discard job %s: %v", job.ID, err)
+		http.Error(w, "DB Error", http.StatusInternalServerError)
+		return
+	}
+	if !claimed {
+		http.Error(w, "This preview is already being imported", http.StatusConflict)
+		return
+	}
+	if err := h.Store.DeletePreviewRows(job.ID); err != nil {
+		log.Printf("Failed to delete preview rows: %v", err)
+		http.Error(w, "DB Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/import")
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleRevert undoes a completed import, removing exactly the quantities it added.
+func (h *Handler) HandleRevert(w http.ResponseWriter, r *http.Request) {
+	job, err := h.Store.GetJob(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	if !job.IsImport() || job.Status != models.JobStatusCompleted {
+		http.Error(w, "Only completed imports can be reverted", http.StatusBadRequest)
+		return
+	}
+
+	// A preview has not added anything yet; it is confirmed or discarded instead
+	var res struct {
+		Preview bool `json:"preview"`
+	}
+	json.Unmarshal([]byte(job.ResultSummary), &res)
+	if res.Preview {
+		http.Error(w, "This import is a preview; confirm or discard it instead", http.StatusBadRequest)
+		return
+	}
+
+	removed, reverted, err := h.Store.RevertJob(job.ID)
+	if err != nil {
+		log.Printf("Failed to revert job %s: %v", job.ID, err)
+		http.Error(w, "DB Error", http.StatusInternalServerError)
+		return
+	}
+	if !reverted {
+		http.Error(w, "This import has changed since the page loaded", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("HX-Trigger", "review-count-updated")
+	fmt.Fprintf(w, `<small>Reverted: %d cards removed</small>`, removed)
+}
+
+// previewJob loads a job whose dry-run results are awaiting confirmation.
+func (h *Handler) previewJob(id string) (*models.Job, error) {
+	job, err := h.Store.GetJob(id)
+	if err != nil {
+		return nil, fmt.Errorf("Job not found")
+	}
+
+	var res struct {
+		Preview bool `json:"preview"`
+	}
+	json.Unmarshal([]byte(job.ResultSummary), &res)
+	if job.Status != models.JobStatusCompleted || !res.Preview {
+		return nil, fmt.Errorf("No preview pending for this job")
+	}
+	return job, nil
+}
+
+// importOptions reads the import options chosen on the upload form.
+func importOptions(r *http.Request) models.ImportOptions {
+	return models.ImportOptions{
+		Preview:  r.FormValue("preview") == "on",
+		Location: strings.TrimSpace(r.FormValue("location")),
+	}
+}
@@ -0,0 +1,144 @@
+package jobs
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+	"github.com/JulianDominic/GatheringTheBulk/internal/worker"
+	"github.com/google/uuid"
+)
+
+// renderMapping shows the detected headers with sample rows and asks the user
+// to map each header to an import field.
+func (h *Handler) renderMapping(w http.ResponseWriter, jobID string, ins *worker.CSVInspection, opts models.ImportOptions, errMsg string) {
+	// Pre-select whatever detection found, keyed by column index
+	selected := make(map[int]string)
+	for field, header := range ins.Fields {
+		for i, h := range ins.Header {
+			if h == header {
+				selected[i] = field
+				break
+			}
+		}
+	}
+
+	data := struct {
+		JobID    string
+		Header   []string
+		Samples  [][]string
+		Fields   []worker.ImportField
+		Selected map[int]string
+		Format   string
+		Preview  bool
+		Location string
+		Error    string
+	}{
+		JobID:    jobID,
+		Header:   ins.Header,
+		Samples:  ins.Samples,
+		Fields:   worker.ImportFields,
+		Selected: selected,
+		Format:   ins.Format,
+		Preview:  opts.Preview,
+		Location: opts.Location,
+		Error:    errMsg,
+	}
+
+	h.Renderer.RenderPartial(w, "partials/column_mapping.html", data)
+}
+
+// HandleImportMapping queues an uploaded CSV using the column mapping the
+// user chose, optionally saving it as a named profile.
+func (h *Handler) HandleImportMapping(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	if _, err := uuid.Parse(jobID); err != nil {
+		http.Error(w, "Invalid upload", http.StatusBadRequest)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	if !h.uploadUnclaimed(jobID) {
+		http.Error(w, "This upload is already being imported", http.StatusConflict)
+		return
+	}
+
+	path := filepath.Join("uploads", jobID+".csv")
+	ins, err := worker.InspectCSV(path, 5)
+	if err != nil {
+		http.Error(w, "Upload not found, please upload the file again", http.StatusNotFound)
+		return
+	}
+
+	opts := importOptions(r)
+	opts.Mapping = make(map[string]string)
+	for i, header := range ins.Header {
+		if field := r.FormValue("col_" + strconv.Itoa(i)); field != "" {
+			opts.Mapping[field] = header
+		}
+	}
+
+	if !worker.MappingUsable(opts.Mapping) {
+		h.renderMapping(w, jobID, ins, opts, "Map a Card Name column, both Set Code and Collector Number, or a Scryfall ID.")
+		return
+	}
+
+	if name := strings.TrimSpace(r.FormValue("profile_name")); name != "" {
+		if err := h.Store.SaveImportProfile(name, opts.Mapping); err != nil {
+			log.Printf("Failed to save import profile: %v", err)
+		}
+		opts.Profile = name
+	}
+
+	h.queueImport(w, jobID, models.JobTypeCSVImport, opts, path)
+}
+
+// HandleDeleteProfile removes a saved column mapping.
+func (h *Handler) HandleDeleteProfile(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Store.DeleteImportProfile(id); err != nil {
+		log.Printf("Failed to delete import profile: %v", err)
+		http.Error(w, "Internal Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleCancelMapping throws away an upload the user chose not to map.
+func (h *Handler) HandleCancelMapping(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	if _, err := uuid.Parse(jobID); err != nil {
+		http.Error(w, "Invalid upload", http.StatusBadRequest)
+		return
+	}
+	if !h.uploadUnclaimed(jobID) {
+		http.Error(w, "This upload is already being imported", http.StatusConflict)
+		return
+	}
+	if err := os.Remove(filepath.Join("uploads", jobID+".csv")); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove upload %s: %v", jobID, err)
+	}
+	fmt.Fprint(w, `<small>Upload cancelled.</small>`)
+}
+
+// uploadUnclaimed reports whether no job has been created for the upload
+// with this ID yet, so its file may still be mapped or thrown away.
+func (h *Handler) uploadUnclaimed(jobID string) bool {
+	_, err := h.Store.GetJob(jobID)
+	return errors.Is(err, sql.ErrNoRows)
+}
@@ -1,18 +1,26 @@
 package pages
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/JulianDominic/GatheringTheBulk/internal/api/common"
+	"github.com/JulianDominic/GatheringTheBulk/internal/matcher"
 	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+	"github.com/JulianDominic/GatheringTheBulk/internal/scryfall"
 	"github.com/JulianDominic/GatheringTheBulk/internal/store"
+	"github.com/JulianDominic/GatheringTheBulk/internal/worker"
 )
 
 type Handler struct {
-	Store    store.Store
-	Renderer *common.Renderer
+	Store     store.Store
+	Renderer  *common.Renderer
+	BackupDir string // Where scheduled backups are written
 }
 
 func (h *Handler) HandleDashboard(w http.ResponseWriter, r *http.Request) {
@@ -22,6 +30,11 @@ func (h *Handler) HandleDashboard(w http.ResponseWriter, r *http.Request) {
 	}
 
 	q := r.URL.Query().Get("q")
+	sort := r.URL.Query().Get("sort")
+	view := r.URL.Query().Get("view") // "cards" groups every printing of a card
+	if view != "cards" {
+		view = ""
+	}
 	pageSize := 20
 	page := 1
 	if p := r.URL.Query().Get("page"); p != "" {
@@ -31,11 +44,37 @@ func (h *Handler) HandleDashboard(w http.ResponseWriter, r *http.Request) {
 	}
 	offset := (page - 1) * pageSize
 
-	items, total, err := h.Store.ListInventory(pageSize, offset, q)
+	var (
+		items  []models.InventoryItem
+		groups []models.InventoryGroup
+		total  int
+		err    error
+	)
+	if view == "cards" {
+		groups, total, err = h.Store.ListInventoryGroups(pageSize, offset, q, sort)
+	} else {
+		items, total, err = h.Store.ListInventory(pageSize, offset, q, sort)
+	}
 	if err != nil {
 		log.Printf("Error listing inventory: %v", err)
 	}
 
+	value, unpriced, err := h.Store.InventoryValue(q)
+	if err != nil {
+		log.Printf("Error valuing inventory: %v", err)
+	}
+
+	sets, err := h.Store.ListInventorySets()
+	if err != nil {
+		log.Printf("Error listing sets: %v", err)
+	}
+	var setFilter string
+	for _, word := range strings.Fields(q) {
+		if code, ok := strings.CutPrefix(strings.ToLower(word), "set:"); ok {
+			setFilter = code
+		}
+	}
+
 	totalPages := (total + pageSize - 1) / pageSize
 	if totalPages < 1 {
 		totalPages = 1
@@ -43,8 +82,15 @@ func (h *Handler) HandleDashboard(w http.ResponseWriter, r *http.Request) {
 
 	data := struct {
 		Items      []models.InventoryItem
+		Groups     []models.InventoryGroup
+		View       string
 		Total      int
+		Value      float64
+		Unpriced   int
 		Query      string
+		Sort       string
+		Sets       []models.Set
+		SetFilter  string
 		Page       int
 		TotalPages int
 		PageSize   int
@@ -52,8 +98,15 @@ func (h *Handler) HandleDashboard(w http.ResponseWriter, r *http.Request) {
 		HasNext    bool
 	}{
 		Items:      items,
+		Groups:     groups,
+		View:       view,
 		Total:      total,
+		Value:      value,
+		Unpriced:   unpriced,
 		Query:      q,
+		Sort:       sort,
+		Sets:       sets,
+		SetFilter:  setFilter,
 		Page:       page,
 		TotalPages: totalPages,
 		PageSize:   pageSize,
@@ -70,15 +123,119 @@ func (h *Handler) HandleSettings(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Error fetching setting: %v", err)
 	}
 
+	threshold, err := h.Store.GetSetting(matcher.ThresholdSetting)
+	if err != nil {
+		log.Printf("Error fetching setting: %v", err)
+	}
+	if threshold == "" {
+		threshold = strconv.Itoa(matcher.DefaultThreshold)
+	}
+
+	schedule, _ := h.Store.GetSetting(worker.BackupScheduleSetting)
+	if schedule == "" {
+		schedule = "off"
+	}
+	backupTime, _ := h.Store.GetSetting(worker.BackupTimeSetting)
+	if backupTime == "" {
+		backupTime = worker.DefaultBackupTime
+	}
+	retention, _ := h.Store.GetSetting(worker.BackupRetentionSetting)
+	if retention == "" {
+		retention = strconv.Itoa(worker.DefaultBackupRetention)
+	}
+
 	data := struct {
-		LastSync string
+		LastSync        string
+		BulkType        string
+		FuzzyThreshold  string
+		BackupDir       string
+		BackupSchedule  string
+		BackupTime      string
+		BackupRetention string
 	}{
-		LastSync: lastSync,
+		LastSync:        lastSync,
+		BulkType:        worker.BulkType(h.Store),
+		FuzzyThreshold:  threshold,
+		BackupDir:       h.BackupDir,
+		BackupSchedule:  schedule,
+		BackupTime:      backupTime,
+		BackupRetention: retention,
 	}
 
 	h.Renderer.Render(w, r, "settings.html", data)
 }
 
+// HandleSaveMatching stores the import matching preferences.
+func (h *Handler) HandleSaveMatching(w http.ResponseWriter, r *http.Request) {
+	threshold, err := strconv.Atoi(r.FormValue("fuzzy_match_threshold"))
+	if err != nil || threshold < 0 || threshold > 100 {
+		http.Error(w, "Threshold must be a whole number from 0 to 100", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Store.SetSetting(matcher.ThresholdSetting, strconv.Itoa(threshold)); err != nil {
+		log.Printf("Failed to save setting: %v", err)
+		http.Error(w, "DB Error", http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Fprint(w, `<small>Saved.</small>`)
+}
+
+// HandleSaveSync stores which Scryfall bulk file the sync downloads.
+func (h *Handler) HandleSaveSync(w http.ResponseWriter, r *http.Request) {
+	bulkType := r.FormValue("bulk_type")
+	if bulkType != scryfall.DefaultCards && bulkType != scryfall.AllCards {
+		http.Error(w, "Unknown bulk data type", http.StatusBadRequest)
+		return
+	}
+
+	if err := worker.SaveBulkType(h.Store, bulkType); err != nil {
+		log.Printf("Failed to save setting: %v", err)
+		http.Error(w, "DB Error", http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Fprint(w, `<small>Saved. Run a sync to download it.</small>`)
+}
+
+// HandleSaveBackup stores the automatic backup schedule and retention.
+func (h *Handler) HandleSaveBackup(w http.ResponseWriter, r *http.Request) {
+	schedule := r.FormValue("backup_schedule")
+	if schedule != "off" && schedule != "daily" && schedule != "weekly" {
+		http.Error(w, "Unknown schedule", http.StatusBadRequest)
+		return
+	}
+	backupTime := r.FormValue("backup_time")
+	if _, err := time.Parse("15:04", backupTime); err != nil {
+		http.Error(w, "Time must be in HH:MM format", http.StatusBadRequest)
+		return
+	}
+	retention, err := strconv.Atoi(r.FormValue("backup_retention"))
+	if err != nil || retention < 1 {
+		http.Error(w, "Keep at least one backup", http.StatusBadRequest)
+		return
+	}
+
+	settings := map[string]string{
+		worker.BackupScheduleSetting:  schedule,
+		worker.BackupTimeSetting:      backupTime,
+		worker.BackupRetentionSetting: strconv.Itoa(retention),
+	}
+	for key, value := range settings {
+		if err := h.Store.SetSetting(key, value); err != nil {
+			log.Printf("Failed to save setting: %v", err)
+			http.Error(w, "DB Error", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := worker.MarkBackupScheduled(h.Store); err != nil {
+		log.Printf("Failed to save setting: %v", err)
+	}
+
+	fmt.Fprint(w, `<small>Saved.</small>`)
+}
+
 func (h *Handler) HandleImportHub(w http.ResponseWriter, r *http.Request) {
 	tab := r.URL.Query().Get("tab")
 	if tab == "" {
@@ -87,15 +244,137 @@ func (h *Handler) HandleImportHub(w http.ResponseWriter, r *http.Request) {
 
 	count, _ := h.Store.CountReviewItems()
 
+	var profiles []models.ImportProfile
+	var locations []string
+	if tab == "upload" {
+		var err error
+		if profiles, err = h.Store.ListImportProfiles(); err != nil {
+			log.Printf("Error listing import profiles: %v", err)
+		}
+		if locations, err = h.Store.ListLocations(); err != nil {
+			log.Printf("Error listing locations: %v", err)
+		}
+	}
+
+	var history []jobView
+	if tab == "history" {
+		jobs, err := h.Store.ListJobs(50)
+		if err != nil {
+			log.Printf("Error listing jobs: %v", err)
+		}
+		for _, job := range jobs {
+			history = append(history, newJobView(job))
+		}
+	}
+
 	data := struct {
 		ReviewCount int
 		IsUpload    bool
 		IsReview    bool
+		IsHistory   bool
+		History     []jobView
+		Profiles    []models.ImportProfile
+		Locations   []string
 	}{
 		ReviewCount: count,
 		IsUpload:    tab == "upload",
 		IsReview:    tab == "review",
+		IsHistory:   tab == "history",
+		History:     history,
+		Profiles:    profiles,
+		Locations:   locations,
 	}
 
 	h.Renderer.Render(w, r, "import.html", data)
 }
+
+func (h *Handler) HandleImportPreview(w http.ResponseWriter, r *http.Request) {
+	job, err := h.Store.GetJob(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	rows, err := h.Store.ListPreviewRows(job.ID)
+	if err != nil {
+		log.Printf("Error listing preview rows: %v", err)
+	}
+
+	type previewLine struct {
+		models.PreviewRow
+		RawDataMap        map[string]string
+		ProposedValuesMap map[string]interface{}
+		CandidateList     []models.MatchCandidate
+	}
+
+	// Totals per status: number of rows and number of cards
+	type total struct {
+		Rows  int
+		Cards int
+	}
+	totals := map[string]*total{"MATCHED": {}, "AMBIGUOUS": {}, "NOT_FOUND": {}}
+
+	lines := make([]previewLine, 0, len(rows))
+	for _, row := range rows {
+		line := previewLine{PreviewRow: row, CandidateList: models.ParseCandidates(row.Candidates)}
+		json.Unmarshal([]byte(row.RawData), &line.RawDataMap)
+		json.Unmarshal([]byte(row.ProposedValues), &line.ProposedValuesMap)
+		lines = append(lines, line)
+
+		t, ok := totals[row.Status]
+		if !ok {
+			t = &total{}
+			totals[row.Status] = t
+		}
+		t.Rows++
+		if qty, ok := line.ProposedValuesMap["quantity"].(float64); ok {
+			t.Cards += int(qty)
+		}
+	}
+
+	data := struct {
+		Job       *models.Job
+		Rows      []previewLine
+		Totals    map[string]*total
+		IsPending bool
+	}{
+		Job:       job,
+		Rows:      lines,
+		Totals:    totals,
+		IsPending: job.Status == models.JobStatusCompleted && len(rows) > 0,
+	}
+
+	h.Renderer.Render(w, r, "import_preview.html", data)
+}
+
+// jobView is a job as shown in the history list.
+type jobView struct {
+	models.Job
+	Description string
+	CanRevert   bool
+	HasPreview  bool
+}
+
+func newJobView(job models.Job) jobView {
+	v := jobView{Job: job, Description: job.ResultSummary}
+	if !job.IsImport() || job.Status == models.JobStatusFailed || job.ResultSummary == "" {
+		return v
+	}
+
+	var res struct {
+		Success int    `json:"success"`
+		Review  int    `json:"review"`
+		Format  string `json:"format"`
+		Preview bool   `json:"preview"`
+	}
+	json.Unmarshal([]byte(job.ResultSummary), &res)
+
+	if res.Preview {
+		v.Description = fmt.Sprintf("%s preview: %d would be added, %d would need review", res.Format, res.Success, res.Review)
+	} else {
+		v.Description = fmt.Sprintf("%s: %d added, %d sent to review", res.Format, res.Success, res.Review)
+	}
+	v.HasPreview = res.Preview && job.Status == models.JobStatusCompleted
+	v.CanRevert = job.Status == models.JobStatusCompleted && !res.Preview
+	return v
+}
@@ -70,10 +70,12 @@ func (h *Handler) HandleResolveModal(w http.ResponseWriter, r *http.Request) {
 		models.ReviewItem
 		RawDataMap        map[string]string
 		ProposedValuesMap map[string]interface{}
+		CandidateList     []models.MatchCandidate
 	}{
 		ReviewItem:        *item,
 		RawDataMap:        rawData,
 		ProposedValuesMap: proposedValues,
+		CandidateList:     models.ParseCandidates(item.Candidates),
 	}
 
 	h.Renderer.RenderPartial(w, "partials/resolve_modal.html", data)
@@ -101,11 +103,13 @@ func (h *Handler) HandleResolveSelect(w http.ResponseWriter, r *http.Request) {
 
 	data := struct {
 		*store.CardSearchResult
-		QueueID   int
-		Quantity  interface{}
-		Condition interface{}
-		IsFoil    interface{}
-		Language  interface{}
+		QueueID       int
+		Quantity      interface{}
+		Condition     interface{}
+		IsFoil        interface{}
+		Language      interface{}
+		Location      interface{}
+		PurchasePrice interface{}
 	}{
 		CardSearchResult: card,
 		QueueID:          id,
@@ -113,6 +117,8 @@ func (h *Handler) HandleResolveSelect(w http.ResponseWriter, r *http.Request) {
 		Condition:        proposedValues["condition"],
 		IsFoil:           proposedValues["is_foil"],
 		Language:         proposedValues["language"],
+		Location:         proposedValues["location"],
+		PurchasePrice:    proposedValues["purchase_price"],
 	}
 
 	h.Renderer.RenderPartial(w, "partials/resolve_select.html", data)
@@ -126,6 +132,9 @@ func (h *Handler) HandleResolve(w http.ResponseWriter, r *http.Request) {
 		Condition  string `json:"condition"`
 		IsFoil     bool   `json:"is_foil"`
 		Language   string `json:"language"`
+		Location   string `json:"location"`
+
+		PurchasePrice float64 `json:"purchase_price"`
 	}
 
 	if r.Header.Get("Content-Type") == "application/json" {
@@ -141,6 +150,14 @@ func (h *Handler) HandleResolve(w http.ResponseWriter, r *http.Request) {
 		req.Condition = r.FormValue("condition")
 		req.IsFoil = r.FormValue("is_foil") == "true" || r.FormValue("is_foil") == "on"
 		req.Language = r.FormValue("language")
+		req.Location = r.FormValue("location")
+		req.PurchasePrice, _ = strconv.ParseFloat(r.FormValue("purchase_price"), 64)
+	}
+
+	reviewItem, err := h.Store.GetReviewItem(req.QueueID)
+	if err != nil {
+		http.Error(w, "Review item not found", http.StatusNotFound)
+		return
 	}
 
 	item := models.InventoryItem{
@@ -149,10 +166,18 @@ func (h *Handler) HandleResolve(w http.ResponseWriter, r *http.Request) {
 		Condition:  req.Condition,
 		IsFoil:     req.IsFoil,
 		Language:   req.Language,
-		Location:   "Imported",
+		Location:   req.Location,
+
+		PurchasePrice: req.PurchasePrice,
+	}
+
+	// Resolutions keep the location their row was imported with
+	if item.Location == "" {
+		item.Location = "Imported"
 	}
 
-	if err := h.Store.AddInventory(item); err != nil {
+	// Attribute the card to the original import so reverting it covers resolutions too
+	if err := h.Store.AddJobInventory(reviewItem.JobID, reviewItem.RowIndex, item); err != nil {
 		log.Printf("Resolved item add failed: %v", err)
 		http.Error(w, "DB Error", http.StatusInternalServerError)
 		return
@@ -0,0 +1,131 @@
+package value
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/api/common"
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+	"github.com/JulianDominic/GatheringTheBulk/internal/store"
+)
+
+// periods are the ranges offered on the value page, in days. Zero is all
+// recorded history.
+var periods = []int{7, 30, 90, 365, 0}
+
+// maxMovers is how many price movers are listed.
+const maxMovers = 25
+
+type Handler struct {
+	Store    store.Store
+	Renderer *common.Renderer
+}
+
+// HandlePage charts the collection's value over a period and lists the owned
+// cards whose prices moved most in it.
+func (h *Handler) HandlePage(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d >= 0 {
+		days = d
+	}
+
+	var since string
+	if days > 0 {
+		since = time.Now().AddDate(0, 0, -days).Format("2006-01-02")
+	}
+
+	points, err := h.Store.ListCollectionValues(since)
+	if err != nil {
+		log.Printf("Error listing collection values: %v", err)
+	}
+
+	// Movers need a starting price; for all history, use the first recorded day
+	moversSince := since
+	if moversSince == "" && len(points) > 0 {
+		moversSince = points[0].Date
+	}
+	movers, err := h.Store.ListPriceMovers(moversSince, maxMovers)
+	if err != nil {
+		log.Printf("Error listing price movers: %v", err)
+	}
+
+	data := struct {
+		Days    int
+		Periods []int
+		Points  []models.ValuePoint
+		Latest  *models.ValuePoint
+		Change  float64
+		Chart   chart
+		Movers  []models.PriceMover
+	}{
+		Days:    days,
+		Periods: periods,
+		Points:  points,
+		Chart:   newChart(points),
+		Movers:  movers,
+	}
+	if len(points) > 0 {
+		data.Latest = &points[len(points)-1]
+		data.Change = data.Latest.USD - points[0].USD
+	}
+
+	h.Renderer.Render(w, r, "value.html", data)
+}
+
+// Chart area in SVG user units.
+const (
+	chartWidth  = 600
+	chartHeight = 200
+)
+
+// chart is a line chart of collection value, drawn by the template as SVG.
+type chart struct {
+	Width, Height int
+	Line          string // Polyline points
+	Area          string // Polygon points, the line closed along the bottom
+	Min, Max      float64
+	From, To      string
+}
+
+func newChart(points []models.ValuePoint) chart {
+	c := chart{Width: chartWidth, Height: chartHeight}
+	if len(points) == 0 {
+		return c
+	}
+
+	c.Min, c.Max = points[0].USD, points[0].USD
+	for _, p := range points {
+		c.Min = min(c.Min, p.USD)
+		c.Max = max(c.Max, p.USD)
+	}
+	c.From, c.To = points[0].Date, points[len(points)-1].Date
+
+	// Days are placed by date, so gaps in the record show as gaps in time
+	first, _ := time.Parse("2006-01-02", c.From)
+	last, _ := time.Parse("2006-01-02", c.To)
+	span := last.Sub(first).Hours()
+
+	coords := make([]string, len(points))
+	for i, p := range points {
+		x := float64(chartWidth) / 2
+		if span > 0 {
+			t, _ := time.Parse("2006-01-02", p.Date)
+			x = t.Sub(first).Hours() / span * chartWidth
+		}
+		y := float64(chartHeight) / 2
+		if c.Max > c.Min {
+			y = chartHeight - (p.USD-c.Min)/(c.Max-c.Min)*chartHeight
+		}
+		coords[i] = fmt.Sprintf("%.1f,%.1f", x, y)
+	}
+	c.Line = strings.Join(coords, " ")
+
+	firstX := strings.Split(coords[0], ",")[0]
+	lastX := strings.Split(coords[len(coords)-1], ",")[0]
+	c.Area = fmt.Sprintf("%s,%d %s %s,%d", firstX, chartHeight, c.Line, lastX, chartHeight)
+	return c
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	"modernc.org/sqlite"
+)
+
+// requiredColumns are the tables and columns a file must have to be accepted
+// as a backup. Columns added since the first release are left out, as older
+// backups get them from the migrations after restoring.
+var requiredColumns = map[string][]string{
+	"cards":           {"scryfall_id", "name", "set_code", "collector_number"},
+	"inventory":       {"id", "scryfall_id", "quantity", "condition", "is_foil", "language", "location"},
+	"jobs":            {"id", "type", "status"},
+	"review_queue":    {"id", "job_id", "issue_type", "raw_data", "proposed_values"},
+	"system_settings": {"key", "value"},
+}
+
+// Backup writes a consistent snapshot of db to path, which must not exist.
+// It is safe to run while the database is in use.
+func Backup(db *sql.DB, path string) error {
+	if _, err := db.Exec("VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return nil
+}
+
+// ValidateBackup checks that the file at path is an intact SQLite database
+// with the tables of a GatheringTheBulk collection.
+func ValidateBackup(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	magic := make([]byte, 16)
+	_, err = io.ReadFull(f, magic)
+	f.Close()
+	if err != nil || !bytes.Equal(magic, []byte("SQLite format 3\x00")) {
+		return fmt.Errorf("not a SQLite database")
+	}
+
+	db, err := sql.Open("sqlite", readOnlyURI(path))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var check string
+	if err := db.QueryRow("PRAGMA quick_check").Scan(&check); err != nil {
+		return fmt.Errorf("could not read database: %w", err)
+	}
+	if check != "ok" {
+		return fmt.Errorf("database is corrupt: %s", check)
+	}
+
+	for table, columns := range requiredColumns {
+		have, err := tableColumns(db, table)
+		if err != nil {
+			return fmt.Errorf("could not read table %s: %w", table, err)
+		}
+		if len(have) == 0 {
+			return fmt.Errorf("missing table %s", table)
+		}
+		for _, c := range columns {
+			if !have[c] {
+				return fmt.Errorf("table %s is missing column %s", table, c)
+			}
+		}
+	}
+	return nil
+}
+
+// Restore replaces the contents of db with the backup at path, using the
+// SQLite online backup API so open connections stay valid, then upgrades
+// the restored schema. Validate the file with ValidateBackup first.
+func Restore(db *sql.DB, path string) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	err = conn.Raw(func(driverConn any) error {
+		restorer, ok := driverConn.(interface {
+			NewRestore(srcURI string) (*sqlite.Backup, error)
+		})
+		if !ok {
+			return fmt.Errorf("driver does not support restoring")
+		}
+
+		restore, err := restorer.NewRestore(readOnlyURI(path))
+		if err != nil {
+			return err
+		}
+		if _, err := restore.Step(-1); err != nil {
+			restore.Finish()
+			return err
+		}
+		return restore.Finish()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+
+	return upgrade(db)
+}
+
+func readOnlyURI(path string) string {
+	return "file:" + (&url.URL{Path: path}).EscapedPath() + "?mode=ro"
+}
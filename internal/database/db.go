@@ -36,8 +36,18 @@ func InitDB(dsn string) error {
 		return fmt.Errorf("failed to set busy_timeout: %w", err)
 	}
 
+	return upgrade(DB)
+}
+
+// upgrade brings a database created by any version up to the current schema.
+func upgrade(db *sql.DB) error {
+	// Bring tables from older versions up to date
+	if err := migrate(db); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
 	// Apply Schema
-	if _, err := DB.Exec(schemaSQL); err != nil {
+	if _, err := db.Exec(schemaSQL); err != nil {
 		return fmt.Errorf("failed to apply schema: %w", err)
 	}
 
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// columnMigrations lists columns added to tables after they were first
+// released. schema.sql already contains them for fresh databases; existing
+// databases get them through ALTER TABLE before the schema is applied, so
+// that indexes in schema.sql may reference them.
+var columnMigrations = []struct {
+	Table      string
+	Column     string
+	Definition string
+}{
+	{"cards", "set_name", "TEXT"},
+	{"cards", "layout", "TEXT"},
+	{"cards", "mtgo_id", "INTEGER"},
+	{"cards", "oracle_id", "TEXT"},
+	{"cards", "lang", "TEXT"},
+	{"cards", "printed_name", "TEXT"},
+	{"cards", "released_at", "TEXT"},
+	{"cards", "rarity", "TEXT"},
+	{"cards", "type_line", "TEXT"},
+	{"cards", "mana_cost", "TEXT"},
+	{"cards", "cmc", "REAL"},
+	{"cards", "colors", "TEXT"},
+	{"cards", "color_identity", "TEXT"},
+	{"cards", "finishes", "TEXT"},
+	{"jobs", "params", "TEXT"},
+	{"inventory", "purchase_price", "REAL DEFAULT 0"},
+	{"inventory_changes", "row_index", "INTEGER"},
+	{"review_queue", "row_index", "INTEGER"},
+	{"import_preview", "row_index", "INTEGER"},
+	{"review_queue", "candidates", "TEXT"},
+	{"import_preview", "candidates", "TEXT"},
+}
+
+// migrate brings tables created by older versions up to date.
+func migrate(db *sql.DB) error {
+	for _, m := range columnMigrations {
+		columns, err := tableColumns(db, m.Table)
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 || columns[m.Column] {
+			continue // Table will be created by schema.sql, or is up to date
+		}
+
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", m.Table, m.Column, m.Definition)
+		if _, err := db.Exec(query); err != nil {
+			return fmt.Errorf("failed to add %s.%s: %w", m.Table, m.Column, err)
+		}
+	}
+	return nil
+}
+
+// tableColumns returns the set of column names of a table. It is empty if
+// the table does not exist.
+func tableColumns(db *sql.DB, table string) (map[string]bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   bool
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
@@ -4,12 +4,78 @@ CREATE TABLE IF NOT EXISTS cards (
     name TEXT NOT NULL,
     set_code TEXT NOT NULL,
     collector_number TEXT NOT NULL,
-    image_uri TEXT
+    image_uri TEXT,
+    set_name TEXT,
+    layout TEXT,                      -- Scryfall layout, e.g. 'normal', 'transform', 'split'
+    mtgo_id INTEGER,                  -- MTGO catalog ID, if the printing exists on MTGO
+    oracle_id TEXT,                   -- Shared by every printing of the same card
+    lang TEXT,
+    printed_name TEXT,                -- Name as printed, only set for cards not in English
+    released_at TEXT,                 -- YYYY-MM-DD
+    rarity TEXT,
+    type_line TEXT,
+    mana_cost TEXT,
+    cmc REAL,
+    colors TEXT,                      -- Color letters in WUBRG order, e.g. 'WU'; '' for colorless
+    color_identity TEXT,              -- Same form as colors
+    finishes TEXT                     -- Comma-separated, e.g. 'nonfoil,foil'
     -- Simple index for autocomplete (LIKE queries)
     -- FTS5 could be an option later, but simple index is fine for now as per PRD
 );
 
 CREATE INDEX IF NOT EXISTS idx_cards_name ON cards(name);
+CREATE INDEX IF NOT EXISTS idx_cards_oracle_id ON cards(oracle_id);
+
+-- sets: Scryfall's set list, refreshed at each sync. The icon SVG is kept
+-- locally so pages do not load it from Scryfall.
+CREATE TABLE IF NOT EXISTS sets (
+    code TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    set_type TEXT,
+    released_at TEXT,                 -- YYYY-MM-DD
+    card_count INTEGER,
+    parent_set_code TEXT,
+    icon_svg_uri TEXT,
+    icon_svg TEXT                     -- Downloaded from icon_svg_uri
+);
+
+-- card_prices: Latest Scryfall prices per printing, refreshed at each sync.
+-- A NULL price means Scryfall has none for that finish.
+CREATE TABLE IF NOT EXISTS card_prices (
+    scryfall_id TEXT PRIMARY KEY,
+    usd REAL,
+    usd_foil REAL,
+    usd_etched REAL,
+    eur REAL,
+    eur_foil REAL,
+    tix REAL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(scryfall_id) REFERENCES cards(scryfall_id)
+);
+
+-- price_history: Dated price snapshots per printing. A row is only written
+-- when a sync finds prices different from the latest snapshot, so the price
+-- on any day is the newest row dated on or before it.
+CREATE TABLE IF NOT EXISTS price_history (
+    scryfall_id TEXT NOT NULL,
+    date TEXT NOT NULL,               -- YYYY-MM-DD of the sync
+    usd REAL,
+    usd_foil REAL,
+    usd_etched REAL,
+    eur REAL,
+    eur_foil REAL,
+    tix REAL,
+    PRIMARY KEY (scryfall_id, date)
+);
+
+-- collection_value: Total value of the inventory, recorded daily
+CREATE TABLE IF NOT EXISTS collection_value (
+    date TEXT PRIMARY KEY,            -- YYYY-MM-DD
+    usd REAL NOT NULL,
+    cards INTEGER NOT NULL,           -- Copies owned
+    unpriced INTEGER NOT NULL,        -- Copies with no known price
+    recorded_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
 
 -- inventory: The User's Collection
 CREATE TABLE IF NOT EXISTS inventory (
@@ -20,32 +86,76 @@ CREATE TABLE IF NOT EXISTS inventory (
     is_foil BOOLEAN DEFAULT 0,
     language TEXT DEFAULT 'en',
     location TEXT DEFAULT 'Binder',
+    purchase_price REAL DEFAULT 0,
     FOREIGN KEY(scryfall_id) REFERENCES cards(scryfall_id)
 );
 
 -- jobs: Async Task Tracker
 CREATE TABLE IF NOT EXISTS jobs (
     id TEXT PRIMARY KEY,
-    type TEXT NOT NULL,               -- 'SYNC_DB', 'CSV_IMPORT'
-    status TEXT NOT NULL,             -- 'PENDING', 'PROCESSING', 'COMPLETED', 'FAILED'
+    type TEXT NOT NULL,               -- 'SYNC_DB', 'CSV_IMPORT', 'TEXT_IMPORT'
+    status TEXT NOT NULL,             -- 'PENDING', 'PROCESSING', 'COMPLETED', 'FAILED', 'DISCARDED', 'REVERTED'
     progress_current INTEGER DEFAULT 0,
     progress_total INTEGER DEFAULT 0,
     result_summary TEXT,              -- JSON
-    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    params TEXT                       -- JSON, task options chosen at upload
 );
 
+-- inventory_changes: Quantity added to each inventory row by an import job,
+-- so that the job can be reverted exactly (including merges into existing rows)
+CREATE TABLE IF NOT EXISTS inventory_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    job_id TEXT NOT NULL,
+    inventory_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    row_index INTEGER,                -- Source row, used to resume interrupted imports
+    FOREIGN KEY(job_id) REFERENCES jobs(id),
+    FOREIGN KEY(inventory_id) REFERENCES inventory(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_changes_job ON inventory_changes(job_id);
+
 -- review_queue: Optimistic Import Buffer
 CREATE TABLE IF NOT EXISTS review_queue (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     job_id TEXT NOT NULL,
-    issue_type TEXT NOT NULL,         -- 'AMBIGUOUS', 'NOT_FOUND'
+    issue_type TEXT NOT NULL,         -- 'AMBIGUOUS', 'NOT_FOUND', 'ORPHANED' (printing left the card database)
     raw_data TEXT,                    -- JSON of the imported row
     proposed_values TEXT,             -- JSON of parseable fields
+    row_index INTEGER,                -- Source row, used to resume interrupted imports
+    candidates TEXT,                  -- JSON of fuzzy name suggestions
     FOREIGN KEY(job_id) REFERENCES jobs(id)
 );
 
+CREATE INDEX IF NOT EXISTS idx_review_queue_job ON review_queue(job_id);
+
 -- system_settings: Key-Value Config
 CREATE TABLE IF NOT EXISTS system_settings (
     key TEXT PRIMARY KEY,
     value TEXT
 );
+
+-- import_preview: Would-be results of a dry-run import, awaiting confirmation
+CREATE TABLE IF NOT EXISTS import_preview (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    job_id TEXT NOT NULL,
+    status TEXT NOT NULL,             -- 'MATCHED', 'AMBIGUOUS', 'NOT_FOUND'
+    scryfall_id TEXT,                 -- Set when MATCHED
+    raw_data TEXT,                    -- JSON of the imported row
+    proposed_values TEXT,             -- JSON of parseable fields
+    row_index INTEGER,                -- Source row, used to resume interrupted imports
+    candidates TEXT,                  -- JSON of fuzzy name suggestions
+    FOREIGN KEY(job_id) REFERENCES jobs(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_preview_job ON import_preview(job_id);
+
+-- import_profiles: Saved column mappings for CSV layouts that are not auto-detected
+CREATE TABLE IF NOT EXISTS import_profiles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    mapping TEXT NOT NULL,            -- JSON: field -> CSV header
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,107 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+)
+
+// DeckFormat is a decklist file format for a digital client.
+type DeckFormat struct {
+	Key         string
+	Name        string
+	Extension   string
+	ContentType string
+	Write       func(w io.Writer, cards []models.DeckCard) error
+}
+
+// DeckFormats lists the available decklist formats.
+var DeckFormats = []*DeckFormat{
+	{Key: "arena", Name: "MTG Arena", Extension: "txt", ContentType: "text/plain; charset=utf-8", Write: WriteArena},
+	{Key: "mtgo", Name: "MTGO (.dek)", Extension: "dek", ContentType: "application/xml; charset=utf-8", Write: WriteMTGO},
+}
+
+// LookupDeck returns the decklist format with the given key.
+func LookupDeck(key string) (*DeckFormat, bool) {
+	for _, f := range DeckFormats {
+		if f.Key == key {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// WriteArena writes an Arena import list, e.g. "4 Lightning Bolt (M11) 149".
+func WriteArena(w io.Writer, cards []models.DeckCard) error {
+	if _, err := fmt.Fprintln(w, "Deck"); err != nil {
+		return err
+	}
+	for _, c := range cards {
+		_, err := fmt.Fprintf(w, "%d %s (%s) %s\n", c.Quantity, deckName(c, " // "), strings.ToUpper(c.SetCode), c.CollectorNumber)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type dekFile struct {
+	XMLName              xml.Name  `xml:"Deck"`
+	XSD                  string    `xml:"xmlns:xsd,attr"`
+	XSI                  string    `xml:"xmlns:xsi,attr"`
+	NetDeckID            int       `xml:"NetDeckID"`
+	PreconstructedDeckID int       `xml:"PreconstructedDeckID"`
+	Cards                []dekCard `xml:"Cards"`
+}
+
+type dekCard struct {
+	CatID      int    `xml:"CatID,attr,omitempty"`
+	Quantity   int    `xml:"Quantity,attr"`
+	Sideboard  bool   `xml:"Sideboard,attr"`
+	Name       string `xml:"Name,attr"`
+	Annotation int    `xml:"Annotation,attr"`
+}
+
+// WriteMTGO writes an MTGO .dek file. Printings without an MTGO catalog ID
+// are listed by name only, which MTGO resolves to any version it has.
+func WriteMTGO(w io.Writer, cards []models.DeckCard) error {
+	deck := dekFile{
+		XSD: "http://www.w3.org/2001/XMLSchema",
+		XSI: "http://www.w3.org/2001/XMLSchema-instance",
+	}
+	for _, c := range cards {
+		deck.Cards = append(deck.Cards, dekCard{
+			CatID:    c.MTGOID,
+			Quantity: c.Quantity,
+			Name:     deckName(c, "/"),
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(deck); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// deckName is the name a client knows a card by. Split and aftermath cards
+// keep both halves, joined by sep; every other multi-face card (transform,
+// modal DFC, adventure, flip) goes by its front face.
+func deckName(c models.DeckCard, sep string) string {
+	faces := strings.Split(c.Name, " // ")
+	if len(faces) == 1 {
+		return c.Name
+	}
+	if c.Layout == "split" || c.Layout == "aftermath" {
+		return strings.Join(faces, sep)
+	}
+	return faces[0]
+}
@@ -0,0 +1,66 @@
+// Package export writes the inventory out in file formats other tools read.
+package export
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+	"github.com/JulianDominic/GatheringTheBulk/internal/store"
+)
+
+// Format is a CSV layout accepted by a collection tool.
+type Format struct {
+	Key    string // Used in URLs
+	Name   string // Shown to the user
+	Header []string
+	Record func(item models.InventoryItem) []string // One row per inventory item
+}
+
+// Formats lists the available export formats. The first is the default.
+var Formats = []*Format{
+	&Native,
+	&Moxfield,
+	&Deckbox,
+	&ManaBox,
+	&TCGplayer,
+}
+
+// Lookup returns the format with the given key.
+func Lookup(key string) (*Format, bool) {
+	for _, f := range Formats {
+		if f.Key == key {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// flushEvery is how many rows are buffered before being sent to the client.
+const flushEvery = 500
+
+// Write streams the inventory matching searchQuery to w in the given format.
+func Write(w io.Writer, f *Format, s store.Store, searchQuery string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(f.Header); err != nil {
+		return err
+	}
+
+	n := 0
+	err := s.EachInventory(searchQuery, func(item models.InventoryItem) error {
+		if err := cw.Write(f.Record(item)); err != nil {
+			return err
+		}
+		if n++; n%flushEvery == 0 {
+			cw.Flush()
+			return cw.Error()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
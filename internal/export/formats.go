@@ -0,0 +1,194 @@
+package export
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+)
+
+// Native matches the GatheringTheBulk import format, so an export can be
+// imported again as-is. scryfall_id pins the exact printing.
+var Native = Format{
+	Key:  "gatheringthebulk",
+	Name: "GatheringTheBulk",
+	Header: []string{
+		"name", "set", "cn", "quantity", "condition", "foil", "language", "location", "purchase_price", "scryfall_id",
+	},
+	Record: func(item models.InventoryItem) []string {
+		return []string{
+			item.CardName,
+			item.SetCode,
+			item.CollectorNumber,
+			strconv.Itoa(item.Quantity),
+			item.Condition,
+			strconv.FormatBool(item.IsFoil),
+			item.Language,
+			item.Location,
+			strconv.FormatFloat(item.PurchasePrice, 'f', -1, 64),
+			item.ScryfallID,
+		}
+	},
+}
+
+// Moxfield is the collection CSV Moxfield exports and imports. Editions are
+// lower-case set codes.
+var Moxfield = Format{
+	Key:  "moxfield",
+	Name: "Moxfield",
+	Header: []string{
+		"Count", "Tradelist Count", "Name", "Edition", "Condition", "Language", "Foil", "Tags",
+		"Last Modified", "Collector Number", "Alter", "Proxy", "Purchase Price",
+	},
+	Record: func(item models.InventoryItem) []string {
+		return []string{
+			strconv.Itoa(item.Quantity),
+			"0",
+			item.CardName,
+			strings.ToLower(item.SetCode),
+			conditionName(item.Condition, nil),
+			languageName(item.Language, map[string]string{"zhs": "Chinese Simplified", "zht": "Chinese Traditional"}),
+			foilFlag(item.IsFoil, "foil", ""),
+			"",
+			"",
+			item.CollectorNumber,
+			"False",
+			"False",
+			price(item.PurchasePrice),
+		}
+	},
+}
+
+// Deckbox identifies sets by their full name and uses its own condition
+// scale.
+var Deckbox = Format{
+	Key:  "deckbox",
+	Name: "Deckbox",
+	Header: []string{
+		"Count", "Tradelist Count", "Name", "Edition", "Card Number", "Condition", "Language", "Foil",
+		"Signed", "Artist Proof", "Altered Art", "Misprint", "Promo", "Textless", "My Price",
+	},
+	Record: func(item models.InventoryItem) []string {
+		return []string{
+			strconv.Itoa(item.Quantity),
+			"0",
+			item.CardName,
+			setName(item),
+			item.CollectorNumber,
+			conditionName(item.Condition, map[string]string{
+				"LP":  "Good (Lightly Played)",
+				"MP":  "Played",
+				"DMG": "Poor",
+			}),
+			languageName(item.Language, nil),
+			foilFlag(item.IsFoil, "foil", ""),
+			"", "", "", "", "", "",
+			dollars(item.PurchasePrice),
+		}
+	},
+}
+
+// ManaBox uses upper-case set codes and the Cardmarket condition scale in
+// snake case.
+var ManaBox = Format{
+	Key:  "manabox",
+	Name: "ManaBox",
+	Header: []string{
+		"Name", "Set code", "Set name", "Collector number", "Foil", "Quantity", "Scryfall ID",
+		"Purchase price", "Condition", "Language",
+	},
+	Record: func(item models.InventoryItem) []string {
+		return []string{
+			item.CardName,
+			strings.ToUpper(item.SetCode),
+			setName(item),
+			item.CollectorNumber,
+			foilFlag(item.IsFoil, "foil", "normal"),
+			strconv.Itoa(item.Quantity),
+			item.ScryfallID,
+			price(item.PurchasePrice),
+			conditionName(item.Condition, map[string]string{
+				"NM":  "near_mint",
+				"LP":  "excellent",
+				"MP":  "good",
+				"HP":  "played",
+				"DMG": "poor",
+			}),
+			strings.ToLower(item.Language),
+		}
+	},
+}
+
+// TCGplayer is the layout of the TCGplayer app's collection CSV.
+var TCGplayer = Format{
+	Key:  "tcgplayer",
+	Name: "TCGplayer",
+	Header: []string{
+		"Quantity", "Name", "Simple Name", "Set", "Card Number", "Set Code", "Printing", "Condition", "Language",
+	},
+	Record: func(item models.InventoryItem) []string {
+		return []string{
+			strconv.Itoa(item.Quantity),
+			item.CardName,
+			item.CardName,
+			setName(item),
+			item.CollectorNumber,
+			strings.ToUpper(item.SetCode),
+			foilFlag(item.IsFoil, "Foil", "Normal"),
+			conditionName(item.Condition, nil),
+			languageName(item.Language, map[string]string{"zhs": "Chinese (S)", "zht": "Chinese (T)"}),
+		}
+	},
+}
+
+// conditionName spells out a condition code, preferring the tool's own word
+// for it from overrides.
+func conditionName(code string, overrides map[string]string) string {
+	if name, ok := overrides[code]; ok {
+		return name
+	}
+	if name, ok := models.ConditionNames[code]; ok {
+		return name
+	}
+	return code
+}
+
+// languageName spells out a language code, preferring the tool's own word
+// for it from overrides.
+func languageName(code string, overrides map[string]string) string {
+	code = strings.ToLower(code)
+	if name, ok := overrides[code]; ok {
+		return name
+	}
+	return models.LanguageName(code)
+}
+
+// setName falls back to the upper-case set code for cards synced before set
+// names were stored.
+func setName(item models.InventoryItem) string {
+	if item.SetName != "" {
+		return item.SetName
+	}
+	return strings.ToUpper(item.SetCode)
+}
+
+func foilFlag(isFoil bool, foil, normal string) string {
+	if isFoil {
+		return foil
+	}
+	return normal
+}
+
+func price(p float64) string {
+	if p == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(p, 'f', 2, 64)
+}
+
+func dollars(p float64) string {
+	if p == 0 {
+		return ""
+	}
+	return "$" + strconv.FormatFloat(p, 'f', 2, 64)
+}
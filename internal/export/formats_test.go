@@ -0,0 +1,115 @@
+package export
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+	"github.com/JulianDominic/GatheringTheBulk/internal/store"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// inventoryStore serves a fixed inventory to Write.
+type inventoryStore struct {
+	store.Store
+	items []models.InventoryItem
+}
+
+func (s inventoryStore) EachInventory(searchQuery string, fn func(models.InventoryItem) error) error {
+	for _, item := range s.items {
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// testInventory covers every condition, foil and non-foil copies, languages
+// with tool-specific names, names that need quoting and a card synced
+// before set names were stored.
+var testInventory = []models.InventoryItem{
+	{
+		ScryfallID: "e3285e6b-3e79-4d7c-bf96-d920f973b122", CardName: "Lightning Bolt", SetCode: "m11",
+		SetName: "Magic 2011", CollectorNumber: "149", Quantity: 4, Condition: "NM", Language: "en",
+		Location: "Binder", PurchasePrice: 1.5,
+	},
+	{
+		ScryfallID: "0b6a8e4b-b4ba-4b62-b9b4-fd1b2b4b6a3c", CardName: "Fire // Ice", SetCode: "mh2",
+		SetName: "Modern Horizons 2", CollectorNumber: "290", Quantity: 1, Condition: "LP", IsFoil: true,
+		Language: "ja", Location: "Trade Box",
+	},
+	{
+		ScryfallID: "9c09b6c3-5d3c-46a1-9e6a-e3a1a6b8a2f1", CardName: "Brainstorm", SetCode: "sta",
+		CollectorNumber: "13", Quantity: 2, Condition: "MP", IsFoil: true, Language: "zhs",
+		Location: "Binder", PurchasePrice: 12.345,
+	},
+	{
+		ScryfallID: "11bf83bb-c95b-4b4f-9a56-ce7a1816307a", CardName: "Delver of Secrets // Insectile Aberration",
+		SetCode: "isd", SetName: "Innistrad", CollectorNumber: "51", Quantity: 3, Condition: "HP",
+		Language: "de", Location: "Deck, \"Blue\"", PurchasePrice: 0.25,
+	},
+	{
+		ScryfallID: "5f8287b1-5bb6-4ee5-9f7e-7d9b7bc7a1b0", CardName: "Sol Ring", SetCode: "c21",
+		SetName: "Commander 2021", CollectorNumber: "263", Quantity: 1, Condition: "DMG", Language: "zht",
+		Location: "Imported", PurchasePrice: 2,
+	},
+}
+
+// testDeck covers a split card, a transform card and a printing without an
+// MTGO catalog ID.
+var testDeck = []models.DeckCard{
+	{Name: "Lightning Bolt", SetCode: "m11", CollectorNumber: "149", Layout: "normal", MTGOID: 37773, Quantity: 4},
+	{Name: "Fire // Ice", SetCode: "mh2", CollectorNumber: "290", Layout: "split", MTGOID: 91003, Quantity: 2},
+	{Name: "Delver of Secrets // Insectile Aberration", SetCode: "isd", CollectorNumber: "51", Layout: "transform", Quantity: 4},
+	{Name: "Sol Ring", SetCode: "c21", CollectorNumber: "263", Layout: "normal", Quantity: 1},
+}
+
+func TestFormats(t *testing.T) {
+	s := inventoryStore{items: testInventory}
+	for _, f := range Formats {
+		t.Run(f.Key, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, f, s, ""); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			checkGolden(t, f.Key+".golden", buf.Bytes())
+		})
+	}
+}
+
+func TestDeckFormats(t *testing.T) {
+	for _, f := range DeckFormats {
+		t.Run(f.Key, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := f.Write(&buf, testDeck); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			checkGolden(t, "deck_"+f.Key+".golden", buf.Bytes())
+		})
+	}
+}
+
+// checkGolden compares got with testdata/name, rewriting the file instead
+// when the tests run with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file: %v (run with -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output does not match %s\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
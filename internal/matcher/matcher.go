@@ -0,0 +1,161 @@
+// Package matcher finds card names that are close to a misspelled one.
+package matcher
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+)
+
+// ThresholdSetting is the system setting holding the auto-accept confidence,
+// as a percentage. Zero turns auto-accepting off.
+const ThresholdSetting = "fuzzy_match_threshold"
+
+// DefaultThreshold is the auto-accept confidence used when the setting is unset.
+const DefaultThreshold = 90
+
+// minScore is the lowest score worth suggesting to the user.
+const minScore = 0.6
+
+type entry struct {
+	Key    string // Normalised name or face name
+	Sorted string // Key with its words sorted
+	Name   string // Full card name to look the card up by
+}
+
+// Index holds the card names to match against.
+type Index struct {
+	entries []entry
+}
+
+// NewIndex builds an index over card names. Double-faced cards are also
+// indexed by each face, since imports often name only the front.
+func NewIndex(names []string) *Index {
+	idx := &Index{}
+	for _, name := range names {
+		idx.add(name, name)
+		if strings.Contains(name, " // ") {
+			for _, face := range strings.Split(name, " // ") {
+				idx.add(face, name)
+			}
+		}
+	}
+	return idx
+}
+
+func (idx *Index) add(key, name string) {
+	k := normalize(key)
+	if k == "" {
+		return
+	}
+	idx.entries = append(idx.entries, entry{Key: k, Sorted: sortWords(k), Name: name})
+}
+
+// Match returns up to limit card names similar to name, best first.
+func (idx *Index) Match(name string, limit int) []models.MatchCandidate {
+	key := normalize(name)
+	if key == "" {
+		return nil
+	}
+	sorted := sortWords(key)
+
+	best := make(map[string]float64)
+	for _, e := range idx.entries {
+		score := similarity(key, e.Key)
+		if s := similarity(sorted, e.Sorted); s > score {
+			score = s
+		}
+		if score >= minScore && score > best[e.Name] {
+			best[e.Name] = score
+		}
+	}
+
+	candidates := make([]models.MatchCandidate, 0, len(best))
+	for name, score := range best {
+		candidates = append(candidates, models.MatchCandidate{Name: name, Score: score})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+// normalize lower-cases a name and reduces punctuation to single spaces, so
+// "Jace the Mind Sculptor" and "Jace, the Mind-Sculptor" compare equal.
+// Apostrophes are dropped rather than spaced ("Urza's" -> "urzas").
+func normalize(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		case r == '\'' || r == '’':
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+func sortWords(s string) string {
+	words := strings.Fields(s)
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+// similarity is the edit distance between a and b scaled to 0..1, where 1
+// means equal.
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+
+	// The length difference alone bounds the score; skip hopeless pairs
+	diff := len(ra) - len(rb)
+	if diff < 0 {
+		diff = -diff
+	}
+	if 1-float64(diff)/float64(longest) < minScore {
+		return 0
+	}
+
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -2,8 +2,50 @@ package models
 
 type Card struct {
 	ScryfallID      string
+	OracleID        string
 	Name            string
+	Lang            string
+	PrintedName     string // Only set for cards not in English
+	ReleasedAt      string // YYYY-MM-DD
 	SetCode         string
+	SetName         string
+	Layout          string
+	MTGOID          int
 	CollectorNumber string
+	Rarity          string
+	TypeLine        string
+	ManaCost        string
+	CMC             float64
+	Colors          string // Color letters in WUBRG order, e.g. "WU"; empty for colorless
+	ColorIdentity   string // Same form as Colors
+	Finishes        string // Comma-separated, e.g. "nonfoil,foil"
 	ImageURI        string
+	Prices          CardPrices
+}
+
+// CardPrices are a printing's market prices. Nil means no price is known.
+type CardPrices struct {
+	USD       *float64
+	USDFoil   *float64
+	USDEtched *float64
+	EUR       *float64
+	EURFoil   *float64
+	Tix       *float64
+}
+
+// CardImage is where a card's image can be downloaded from.
+type CardImage struct {
+	ScryfallID string
+	ImageURI   string
+}
+
+// Set is a Magic set as listed by Scryfall.
+type Set struct {
+	Code          string
+	Name          string
+	SetType       string // e.g. "expansion", "commander", "token"
+	ReleasedAt    string // YYYY-MM-DD
+	CardCount     int
+	ParentSetCode string
+	IconSVGURI    string
 }
@@ -0,0 +1,13 @@
+package models
+
+// DeckCard is one printing in a decklist export, with the quantity summed
+// over every inventory stack of that printing.
+type DeckCard struct {
+	ScryfallID      string
+	Name            string
+	SetCode         string
+	CollectorNumber string
+	Layout          string
+	MTGOID          int
+	Quantity        int
+}
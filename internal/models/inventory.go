@@ -9,9 +9,35 @@ type InventoryItem struct {
 	Language   string `json:"language"`
 	Location   string `json:"location"`
 
+	PurchasePrice float64 `json:"purchase_price"`
+
 	// Joined fields for display (populated via JOINs)
 	CardName        string `json:"card_name"`
 	SetCode         string `json:"set_code"`
+	SetName         string `json:"set_name,omitempty"`
 	CollectorNumber string `json:"collector_number"`
 	ImageURI        string `json:"image_uri"`
+
+	// Price is the current USD price of one copy in this finish; Priced is
+	// false when none is known
+	Price  float64 `json:"price"`
+	Priced bool    `json:"priced"`
+}
+
+// Value is the current worth of the whole row.
+func (i InventoryItem) Value() float64 {
+	return i.Price * float64(i.Quantity)
+}
+
+// InventoryGroup totals the inventory of one card across every printing and
+// language of it, as identified by its oracle ID.
+type InventoryGroup struct {
+	OracleID   string // The Scryfall ID for cards synced without an oracle ID
+	Name       string
+	ScryfallID string // Printing most recently added, to show an image of
+	Quantity   int
+	Printings  int
+	Stacks     int
+	Value      float64 // USD value of the copies with a known price
+	Unpriced   int     // Copies without a known price
 }
@@ -6,13 +6,19 @@ type JobType string
 type JobStatus string
 
 const (
-	JobTypeSyncDB    JobType = "SYNC_DB"
-	JobTypeCSVImport JobType = "CSV_IMPORT"
+	JobTypeSyncDB     JobType = "SYNC_DB"
+	JobTypeCSVImport  JobType = "CSV_IMPORT"
+	JobTypeTextImport JobType = "TEXT_IMPORT"
+	JobTypeBackup     JobType = "BACKUP"
+	JobTypeValuation  JobType = "VALUATION"
+	JobTypeImageCache JobType = "IMAGE_CACHE"
 
 	JobStatusPending    JobStatus = "PENDING"
 	JobStatusProcessing JobStatus = "PROCESSING"
 	JobStatusCompleted  JobStatus = "COMPLETED"
 	JobStatusFailed     JobStatus = "FAILED"
+	JobStatusDiscarded  JobStatus = "DISCARDED"
+	JobStatusReverted   JobStatus = "REVERTED"
 )
 
 type Job struct {
@@ -23,4 +29,42 @@ type Job struct {
 	ProgressTotal   int       `json:"progress_total"`
 	ResultSummary   string    `json:"result_summary"` // JSON string
 	CreatedAt       time.Time `json:"created_at"`
+	Params          string    `json:"params"` // JSON string
+}
+
+// IsImport reports whether the job adds cards to inventory.
+func (j *Job) IsImport() bool {
+	return j.Type == JobTypeCSVImport || j.Type == JobTypeTextImport
+}
+
+// ImportOptions are the choices made when an import is uploaded. They are
+// stored in Job.Params.
+type ImportOptions struct {
+	Preview bool `json:"preview"` // Stage results in import_preview instead of writing inventory
+
+	// Mapping overrides format detection with a user-defined field -> CSV
+	// header mapping. Profile names the saved profile it came from, if any.
+	Mapping map[string]string `json:"mapping,omitempty"`
+	Profile string            `json:"profile,omitempty"`
+
+	// Location is given to rows that do not name their own.
+	Location string `json:"location,omitempty"`
+}
+
+// SyncOptions are the choices made when a Scryfall sync is started. They are
+// stored in Job.Params.
+type SyncOptions struct {
+	Force bool `json:"force"` // Download even if the bulk file has not changed
+
+	// Path is a bulk data file on disk to ingest instead of downloading one.
+	// Uploaded files are deleted once ingested.
+	Path     string `json:"path,omitempty"`
+	Uploaded bool   `json:"uploaded,omitempty"`
+}
+
+// ImportProfile is a saved column mapping for a CSV layout.
+type ImportProfile struct {
+	ID      int               `json:"id"`
+	Name    string            `json:"name"`
+	Mapping map[string]string `json:"mapping"` // field -> CSV header
 }
@@ -0,0 +1,18 @@
+package models
+
+// PreviewRow is the would-be outcome of one row of a dry-run import.
+type PreviewRow struct {
+	ID             int    `json:"id"`
+	JobID          string `json:"job_id"`
+	RowIndex       int    `json:"row_index"`
+	Status         string `json:"status"` // MATCHED, or the review issue type
+	ScryfallID     string `json:"scryfall_id"`
+	RawData        string `json:"raw_data"`        // JSON of imported row
+	ProposedValues string `json:"proposed_values"` // JSON of fields
+	Candidates     string `json:"candidates"`      // JSON of []MatchCandidate, may be empty
+
+	// Joined fields for display (populated via JOINs)
+	CardName        string `json:"card_name"`
+	SetCode         string `json:"set_code"`
+	CollectorNumber string `json:"collector_number"`
+}
@@ -1,9 +1,34 @@
 package models
 
+import "encoding/json"
+
 type ReviewItem struct {
 	ID             int    `json:"id"`
 	JobID          string `json:"job_id"`
+	RowIndex       int    `json:"row_index"`
 	IssueType      string `json:"issue_type"`
 	RawData        string `json:"raw_data"`        // JSON of imported row
 	ProposedValues string `json:"proposed_values"` // JSON of fields
+	Candidates     string `json:"candidates"`      // JSON of []MatchCandidate, may be empty
+}
+
+// MatchCandidate is a card name suggested for a row that did not match
+// exactly, with a similarity score from 0 to 1.
+type MatchCandidate struct {
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+}
+
+// Percent is the score as a whole percentage, for display.
+func (c MatchCandidate) Percent() int {
+	return int(c.Score*100 + 0.5)
+}
+
+// ParseCandidates decodes the Candidates column of a review or preview row.
+func ParseCandidates(data string) []MatchCandidate {
+	var candidates []MatchCandidate
+	if data != "" {
+		json.Unmarshal([]byte(data), &candidates)
+	}
+	return candidates
 }
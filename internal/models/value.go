@@ -0,0 +1,39 @@
+package models
+
+// ValuePoint is the collection's value on one day.
+type ValuePoint struct {
+	Date     string  `json:"date"` // YYYY-MM-DD
+	USD      float64 `json:"usd"`
+	Cards    int     `json:"cards"`
+	Unpriced int     `json:"unpriced"`
+}
+
+// PriceMover is an owned printing, in one finish, whose price has changed.
+type PriceMover struct {
+	ScryfallID      string  `json:"scryfall_id"`
+	Name            string  `json:"name"`
+	SetCode         string  `json:"set_code"`
+	CollectorNumber string  `json:"collector_number"`
+	IsFoil          bool    `json:"is_foil"`
+	Quantity        int     `json:"quantity"`
+	OldPrice        float64 `json:"old_price"`
+	Price           float64 `json:"price"`
+}
+
+// Change is the change in price of one copy.
+func (m PriceMover) Change() float64 {
+	return m.Price - m.OldPrice
+}
+
+// TotalChange is the change in value of every copy owned.
+func (m PriceMover) TotalChange() float64 {
+	return m.Change() * float64(m.Quantity)
+}
+
+// Percent is the change relative to the old price.
+func (m PriceMover) Percent() float64 {
+	if m.OldPrice == 0 {
+		return 0
+	}
+	return m.Change() / m.OldPrice * 100
+}
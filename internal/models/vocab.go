@@ -0,0 +1,84 @@
+package models
+
+import "strings"
+
+// Conditions lists the condition codes stored in inventory, best to worst.
+var Conditions = []string{"NM", "LP", "MP", "HP", "DMG"}
+
+// ConditionNames maps condition codes to their long form.
+var ConditionNames = map[string]string{
+	"NM":  "Near Mint",
+	"LP":  "Lightly Played",
+	"MP":  "Moderately Played",
+	"HP":  "Heavily Played",
+	"DMG": "Damaged",
+}
+
+// conditionAliases maps the vocabularies used by other collection tools
+// (lower-cased, letters only) onto our condition codes.
+var conditionAliases = map[string]string{
+	"nm": "NM", "m": "NM", "mt": "NM", "mint": "NM", "nearmint": "NM",
+	"lp": "LP", "ex": "LP", "sp": "LP", "excellent": "LP", "lightlyplayed": "LP", "lightplayed": "LP",
+	"slightlyplayed": "LP", "goodlightlyplayed": "LP",
+	"mp": "MP", "gd": "MP", "good": "MP", "played": "MP", "moderatelyplayed": "MP",
+	"hp": "HP", "pl": "HP", "heavilyplayed": "HP", "heavyplayed": "HP",
+	"dmg": "DMG", "d": "DMG", "po": "DMG", "poor": "DMG", "damaged": "DMG",
+}
+
+// Languages maps Scryfall language codes to their English names.
+var Languages = map[string]string{
+	"en":  "English",
+	"es":  "Spanish",
+	"fr":  "French",
+	"de":  "German",
+	"it":  "Italian",
+	"pt":  "Portuguese",
+	"ja":  "Japanese",
+	"ko":  "Korean",
+	"ru":  "Russian",
+	"zhs": "Simplified Chinese",
+	"zht": "Traditional Chinese",
+	"he":  "Hebrew",
+	"la":  "Latin",
+	"grc": "Ancient Greek",
+	"ar":  "Arabic",
+	"sa":  "Sanskrit",
+	"ph":  "Phyrexian",
+}
+
+// NormalizeCondition maps a condition as written by any supported tool to
+// one of Conditions. Unknown values are returned trimmed but unchanged.
+func NormalizeCondition(s string) string {
+	s = strings.TrimSpace(s)
+	key := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r
+		}
+		return -1
+	}, strings.ToLower(s))
+	if c, ok := conditionAliases[key]; ok {
+		return c
+	}
+	return s
+}
+
+// NormalizeLanguage maps a language name ("Japanese") or code ("JA") to a
+// lower-cased code. Unknown values are returned lower-cased.
+func NormalizeLanguage(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for code, name := range Languages {
+		if strings.ToLower(name) == s {
+			return code
+		}
+	}
+	return s
+}
+
+// LanguageName returns the English name for a language code, or the code
+// itself if it is not known.
+func LanguageName(code string) string {
+	if name, ok := Languages[strings.ToLower(code)]; ok {
+		return name
+	}
+	return code
+}
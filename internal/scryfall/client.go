@@ -1,55 +1,244 @@
 package scryfall
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"time"
 )
 
-type BulkDataResponse struct {
-	Data []struct {
-		Type        string `json:"type"`
-		DownloadURI string `json:"download_uri"`
-	} `json:"data"`
+// DefaultBaseURL is the root of Scryfall's API.
+const DefaultBaseURL = "https://api.scryfall.com"
+
+// DefaultUserAgent identifies the app to Scryfall, which asks every client
+// to send one.
+const DefaultUserAgent = "GatheringTheBulk/0.1"
+
+// maxRetryAfter caps how long a Retry-After header can make a request wait.
+const maxRetryAfter = time.Minute
+
+// Client talks to Scryfall. Create one with NewClient and adjust its fields
+// before use; tests can point BaseURL at their own server.
+type Client struct {
+	BaseURL    string // API root, without a trailing slash
+	UserAgent  string
+	HTTP       *http.Client
+	Timeout    time.Duration // Bounds API calls; file downloads may take longer
+	MaxRetries int           // Further attempts after a 429, 5xx or network error
+	Backoff    time.Duration // Wait before the first retry, doubled for each one after
 }
 
-// FetchBulkDataURL calls Scryfall API to get the current download link for "default_cards".
-func FetchBulkDataURL() (string, error) {
-	resp, err := http.Get("https://api.scryfall.com/bulk-data")
+// NewClient returns a Client for the real Scryfall API.
+func NewClient() *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = 30 * time.Second
+
+	return &Client{
+		BaseURL:    DefaultBaseURL,
+		UserAgent:  DefaultUserAgent,
+		HTTP:       &http.Client{Transport: transport},
+		Timeout:    30 * time.Second,
+		MaxRetries: 3,
+		Backoff:    time.Second,
+	}
+}
+
+// get requests url, retrying with backoff while Scryfall is rate limiting
+// or failing. The last response is returned whatever its status, so callers
+// check it as usual.
+func (c *Client) get(ctx context.Context, url, accept string) (*http.Response, error) {
+	backoff := c.Backoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", c.UserAgent)
+		req.Header.Set("Accept", accept)
+
+		resp, err := c.HTTP.Do(req)
+		if err == nil && !retryable(resp.StatusCode) {
+			return resp, nil
+		}
+		if attempt >= c.MaxRetries || ctx.Err() != nil {
+			return resp, err
+		}
+
+		wait := backoff
+		if err == nil {
+			if d, ok := retryAfter(resp); ok {
+				wait = d
+			}
+			resp.Body.Close()
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// getJSON decodes the API response at url into v.
+func (c *Client) getJSON(url string, v any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	resp, err := c.get(ctx, url, "application/json;q=0.9,*/*;q=0.8")
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch bulk-data list: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("scryfall api returned status: %d", resp.StatusCode)
+		return fmt.Errorf("scryfall api returned status: %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// retryAfter reads a Retry-After header given in seconds.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs < 0 {
+		return 0, false
 	}
+	return min(time.Duration(secs)*time.Second, maxRetryAfter), true
+}
+
+// BulkData describes one of Scryfall's bulk data files.
+type BulkData struct {
+	Type        string `json:"type"`
+	DownloadURI string `json:"download_uri"`
+	UpdatedAt   string `json:"updated_at"`
+	Size        int64  `json:"size"`
+}
+
+type BulkDataResponse struct {
+	Data []BulkData `json:"data"`
+}
+
+// Bulk data types that can be synced.
+const (
+	DefaultCards = "default_cards" // Every printing, in English where it exists
+	AllCards     = "all_cards"     // Every printing in every language, several times larger
+)
 
+// FetchBulkData calls Scryfall API to get the current bulk file of the given
+// type: its download link, and when it was last regenerated.
+func (c *Client) FetchBulkData(bulkType string) (*BulkData, error) {
 	var result BulkDataResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode bulk-data json: %w", err)
+	if err := c.getJSON(c.BaseURL+"/bulk-data", &result); err != nil {
+		return nil, fmt.Errorf("failed to fetch bulk-data list: %w", err)
 	}
 
 	for _, item := range result.Data {
-		// "default_cards" contains every card in English, no duplicates (mostly).
-		// "oracle_cards" is smaller but might miss printed variations user wants.
-		// PRD says "Default Cards".
-		if item.Type == "default_cards" {
-			return item.DownloadURI, nil
+		if item.Type == bulkType {
+			return &item, nil
 		}
 	}
-	return "", fmt.Errorf("default_cards bulk data type not found in response")
+	return nil, fmt.Errorf("%s bulk data type not found in response", bulkType)
 }
 
-// StreamBulkData initiates the download and returns the stream.
-func StreamBulkData(url string) (io.ReadCloser, error) {
-	resp, err := http.Get(url)
+// StreamBulkData initiates the download and returns the stream. The
+// download has no overall deadline, as the file is hundreds of megabytes
+// (gigabytes for all_cards) and is decoded as it arrives.
+func (c *Client) StreamBulkData(url string) (io.ReadCloser, error) {
+	resp, err := c.get(context.Background(), url, "application/json")
 	if err != nil {
 		return nil, err
 	}
 	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
 		return nil, fmt.Errorf("download failed with status: %d", resp.StatusCode)
 	}
 	return resp.Body, nil
 }
+
+type setsResponse struct {
+	Data []Set `json:"data"`
+}
+
+// FetchSets returns every set Scryfall knows of.
+func (c *Client) FetchSets() ([]Set, error) {
+	var result setsResponse
+	if err := c.getJSON(c.BaseURL+"/sets", &result); err != nil {
+		return nil, fmt.Errorf("failed to fetch sets: %w", err)
+	}
+	return result.Data, nil
+}
+
+type migrationsResponse struct {
+	Data     []Migration `json:"data"`
+	HasMore  bool        `json:"has_more"`
+	NextPage string      `json:"next_page"`
+}
+
+// pageRequestDelay spaces out the requests for a list's pages, as Scryfall
+// asks of clients.
+const pageRequestDelay = 100 * time.Millisecond
+
+// FetchMigrations returns every card migration Scryfall has published,
+// reading all pages of the list.
+func (c *Client) FetchMigrations() ([]Migration, error) {
+	var migrations []Migration
+	url := c.BaseURL + "/migrations"
+	for {
+		var page migrationsResponse
+		if err := c.getJSON(url, &page); err != nil {
+			return nil, fmt.Errorf("failed to fetch migrations: %w", err)
+		}
+		migrations = append(migrations, page.Data...)
+		if !page.HasMore || page.NextPage == "" {
+			return migrations, nil
+		}
+		url = page.NextPage
+		time.Sleep(pageRequestDelay)
+	}
+}
+
+// maxIconBytes bounds a downloaded set icon; real ones are a few kilobytes.
+const maxIconBytes = 1 << 20
+
+// FetchIcon downloads a set icon SVG.
+func (c *Client) FetchIcon(url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	resp, err := c.get(ctx, url, "image/svg+xml,*/*;q=0.8")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("icon download failed with status: %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxIconBytes))
+}
+
+// Download copies the file at url to w, such as a card image.
+func (c *Client) Download(url string, w io.Writer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	resp, err := c.get(ctx, url, "*/*")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download failed with status: %d", resp.StatusCode)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
@@ -0,0 +1,252 @@
+package scryfall
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a test server that answers each request with the next status
+// in its script, then 200 with body once the script runs out.
+type recorder struct {
+	mu       sync.Mutex
+	script   []int
+	header   http.Header // Sent with every scripted status
+	body     string
+	requests []*http.Request
+	times    []time.Time
+}
+
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec.mu.Lock()
+	n := len(rec.requests)
+	rec.requests = append(rec.requests, r)
+	rec.times = append(rec.times, time.Now())
+	rec.mu.Unlock()
+
+	if n < len(rec.script) {
+		for k, v := range rec.header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.script[n])
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, rec.body)
+}
+
+// newTestClient starts a server for rec and returns a client pointed at it
+// that backs off for backoff before its first retry.
+func newTestClient(t *testing.T, rec *recorder, backoff time.Duration) *Client {
+	t.Helper()
+	srv := httptest.NewServer(rec)
+	t.Cleanup(srv.Close)
+
+	c := NewClient()
+	c.BaseURL = srv.URL
+	c.Backoff = backoff
+	return c
+}
+
+const bulkBody = `{"data":[{"type":"default_cards","download_uri":"https://example.test/default.json","updated_at":"2026-10-01T09:00:00+00:00","size":512}]}`
+
+func TestRetriesWithBackoff(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable} {
+		t.Run(fmt.Sprint(status), func(t *testing.T) {
+			rec := &recorder{script: []int{status, status}, body: bulkBody}
+			backoff := 20 * time.Millisecond
+			c := newTestClient(t, rec, backoff)
+
+			bulk, err := c.FetchBulkData(DefaultCards)
+			if err != nil {
+				t.Fatalf("FetchBulkData: %v", err)
+			}
+			if bulk.DownloadURI != "https://example.test/default.json" {
+				t.Errorf("download URI = %q", bulk.DownloadURI)
+			}
+			if len(rec.requests) != 3 {
+				t.Fatalf("made %d requests, want 3", len(rec.requests))
+			}
+			if gap := rec.times[1].Sub(rec.times[0]); gap < backoff {
+				t.Errorf("first retry after %v, want at least %v", gap, backoff)
+			}
+			if gap := rec.times[2].Sub(rec.times[1]); gap < 2*backoff {
+				t.Errorf("second retry after %v, want the backoff doubled to at least %v", gap, 2*backoff)
+			}
+		})
+	}
+}
+
+func TestGivesUpAfterMaxRetries(t *testing.T) {
+	rec := &recorder{script: []int{502, 502, 502, 502, 502}, body: bulkBody}
+	c := newTestClient(t, rec, time.Millisecond)
+	c.MaxRetries = 2
+
+	_, err := c.FetchBulkData(DefaultCards)
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Fatalf("err = %v, want the last status", err)
+	}
+	if len(rec.requests) != 3 {
+		t.Errorf("made %d requests, want 3", len(rec.requests))
+	}
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusNotFound} {
+		t.Run(fmt.Sprint(status), func(t *testing.T) {
+			rec := &recorder{script: []int{status}, body: bulkBody}
+			c := newTestClient(t, rec, time.Millisecond)
+
+			if _, err := c.FetchSets(); err == nil {
+				t.Fatal("FetchSets succeeded after a client error")
+			}
+			if len(rec.requests) != 1 {
+				t.Errorf("made %d requests, want 1", len(rec.requests))
+			}
+		})
+	}
+}
+
+func TestRetriesNetworkErrors(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls++; calls == 1 {
+			// Drop the connection without answering
+			if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+				conn.Close()
+			}
+			return
+		}
+		fmt.Fprint(w, `{"data":[]}`)
+	}))
+	defer srv.Close()
+
+	c := NewClient()
+	c.BaseURL = srv.URL
+	c.Backoff = time.Millisecond
+	if _, err := c.FetchSets(); err != nil {
+		t.Fatalf("FetchSets: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("made %d requests, want 2", calls)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	rec := &recorder{
+		script: []int{http.StatusTooManyRequests},
+		header: http.Header{"Retry-After": {"1"}},
+		body:   `{"data":[]}`,
+	}
+	c := newTestClient(t, rec, time.Millisecond)
+
+	if _, err := c.FetchSets(); err != nil {
+		t.Fatalf("FetchSets: %v", err)
+	}
+	if len(rec.requests) != 2 {
+		t.Fatalf("made %d requests, want 2", len(rec.requests))
+	}
+	if gap := rec.times[1].Sub(rec.times[0]); gap < time.Second {
+		t.Errorf("retried after %v, want Retry-After's 1s over the backoff", gap)
+	}
+}
+
+func TestRetryAfterCap(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"2", 2 * time.Second, true},
+		{"0", 0, true},
+		{"3600", maxRetryAfter, true},
+		{"-5", 0, false},
+		{"Wed, 21 Oct 2026 07:28:00 GMT", 0, false},
+		{"", 0, false},
+	} {
+		resp := &http.Response{Header: http.Header{}}
+		if tc.header != "" {
+			resp.Header.Set("Retry-After", tc.header)
+		}
+		got, ok := retryAfter(resp)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("retryAfter(%q) = %v, %v, want %v, %v", tc.header, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestRequestHeaders(t *testing.T) {
+	rec := &recorder{body: `{"data":[]}`}
+	c := newTestClient(t, rec, time.Millisecond)
+
+	if _, err := c.FetchSets(); err != nil {
+		t.Fatalf("FetchSets: %v", err)
+	}
+	if _, err := c.FetchIcon(c.BaseURL + "/icons/m11.svg"); err != nil {
+		t.Fatalf("FetchIcon: %v", err)
+	}
+
+	for i, want := range []string{"application/json;q=0.9,*/*;q=0.8", "image/svg+xml,*/*;q=0.8"} {
+		r := rec.requests[i]
+		if ua := r.Header.Get("User-Agent"); ua != DefaultUserAgent {
+			t.Errorf("%s: User-Agent = %q, want %q", r.URL.Path, ua, DefaultUserAgent)
+		}
+		if accept := r.Header.Get("Accept"); accept != want {
+			t.Errorf("%s: Accept = %q, want %q", r.URL.Path, accept, want)
+		}
+	}
+
+	c.UserAgent = "CustomAgent/2.0"
+	if _, err := c.FetchSets(); err != nil {
+		t.Fatalf("FetchSets: %v", err)
+	}
+	if ua := rec.requests[2].Header.Get("User-Agent"); ua != "CustomAgent/2.0" {
+		t.Errorf("User-Agent = %q, want the client's own", ua)
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	var paths []string
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.RequestURI())
+		switch r.URL.RequestURI() {
+		case "/api/bulk-data":
+			fmt.Fprint(w, bulkBody)
+		case "/api/sets":
+			fmt.Fprint(w, `{"data":[{"code":"m11","name":"Magic 2011"}]}`)
+		case "/api/migrations":
+			fmt.Fprintf(w, `{"data":[{"old_scryfall_id":"a","new_scryfall_id":"b","migration_strategy":"merge"}],"has_more":true,"next_page":"%s/api/migrations?page=2"}`, srv.URL)
+		case "/api/migrations?page=2":
+			fmt.Fprint(w, `{"data":[{"old_scryfall_id":"c","migration_strategy":"delete"}],"has_more":false}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient()
+	c.BaseURL = srv.URL + "/api"
+	c.MaxRetries = 0
+
+	if _, err := c.FetchBulkData(DefaultCards); err != nil {
+		t.Errorf("FetchBulkData: %v", err)
+	}
+	sets, err := c.FetchSets()
+	if err != nil || len(sets) != 1 || sets[0].Code != "m11" {
+		t.Errorf("FetchSets = %v, %v", sets, err)
+	}
+	migrations, err := c.FetchMigrations()
+	if err != nil || len(migrations) != 2 || migrations[1].MigrationStrategy != "delete" {
+		t.Errorf("FetchMigrations = %v, %v", migrations, err)
+	}
+
+	want := []string{"/api/bulk-data", "/api/sets", "/api/migrations", "/api/migrations?page=2"}
+	if strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Errorf("requested %v, want %v", paths, want)
+	}
+}
@@ -1,14 +1,41 @@
 package scryfall
 
+import "strings"
+
 type Card struct {
 	ID              string     `json:"id"`
+	OracleID        string     `json:"oracle_id"`
 	Name            string     `json:"name"`
+	Lang            string     `json:"lang"`
+	PrintedName     string     `json:"printed_name"`
+	ReleasedAt      string     `json:"released_at"`
 	Set             string     `json:"set"`
+	SetName         string     `json:"set_name"`
+	Layout          string     `json:"layout"`
+	MTGOID          int        `json:"mtgo_id"`
 	CollectorNumber string     `json:"collector_number"`
+	Rarity          string     `json:"rarity"`
+	TypeLine        string     `json:"type_line"`
+	ManaCost        string     `json:"mana_cost"`
+	CMC             float64    `json:"cmc"`
+	Colors          []string   `json:"colors"`
+	ColorIdentity   []string   `json:"color_identity"`
+	Finishes        []string   `json:"finishes"`
+	Prices          Prices     `json:"prices"`
 	ImageURIs       *ImageURIs `json:"image_uris"`
 	CardFaces       []CardFace `json:"card_faces"`
 }
 
+// Prices are decimal strings, or null when Scryfall has no price.
+type Prices struct {
+	USD       *string `json:"usd"`
+	USDFoil   *string `json:"usd_foil"`
+	USDEtched *string `json:"usd_etched"`
+	EUR       *string `json:"eur"`
+	EURFoil   *string `json:"eur_foil"`
+	Tix       *string `json:"tix"`
+}
+
 type ImageURIs struct {
 	Small  string `json:"small"`
 	Normal string `json:"normal"`
@@ -16,7 +43,12 @@ type ImageURIs struct {
 }
 
 type CardFace struct {
-	ImageURIs *ImageURIs `json:"image_uris"`
+	OracleID    string     `json:"oracle_id"`
+	PrintedName string     `json:"printed_name"`
+	ManaCost    string     `json:"mana_cost"`
+	TypeLine    string     `json:"type_line"`
+	Colors      []string   `json:"colors"`
+	ImageURIs   *ImageURIs `json:"image_uris"`
 }
 
 // GetFrontImage returns the URL of the front face.
@@ -29,3 +61,92 @@ func (c *Card) GetFrontImage() string {
 	}
 	return "" // Placeholder or empty
 }
+
+// GetOracleID returns the card's oracle ID. Reversible cards only carry it
+// on their faces.
+func (c *Card) GetOracleID() string {
+	if c.OracleID != "" {
+		return c.OracleID
+	}
+	for _, f := range c.CardFaces {
+		if f.OracleID != "" {
+			return f.OracleID
+		}
+	}
+	return ""
+}
+
+// GetManaCost returns the mana cost, joining the faces' costs for
+// double-faced cards, which have none of their own. Faces without a cost,
+// like the back of a transforming card, are left out.
+func (c *Card) GetManaCost() string {
+	if c.ManaCost != "" {
+		return c.ManaCost
+	}
+	var costs []string
+	for _, f := range c.CardFaces {
+		if f.ManaCost != "" {
+			costs = append(costs, f.ManaCost)
+		}
+	}
+	return strings.Join(costs, " // ")
+}
+
+// GetTypeLine returns the type line, joining the faces' for the few layouts
+// that have none of their own.
+func (c *Card) GetTypeLine() string {
+	if c.TypeLine != "" {
+		return c.TypeLine
+	}
+	var types []string
+	for _, f := range c.CardFaces {
+		if f.TypeLine != "" {
+			types = append(types, f.TypeLine)
+		}
+	}
+	return strings.Join(types, " // ")
+}
+
+// GetPrintedName returns the name printed on a card that is not in English,
+// joining the faces' for double-faced cards. It is empty for English cards.
+func (c *Card) GetPrintedName() string {
+	if c.PrintedName != "" {
+		return c.PrintedName
+	}
+	var names []string
+	for _, f := range c.CardFaces {
+		if f.PrintedName != "" {
+			names = append(names, f.PrintedName)
+		}
+	}
+	return strings.Join(names, " // ")
+}
+
+// GetColors returns the card's colors. Double-faced cards only carry them on
+// their faces, so the front face's are used.
+func (c *Card) GetColors() []string {
+	if c.Colors != nil || len(c.CardFaces) == 0 {
+		return c.Colors
+	}
+	return c.CardFaces[0].Colors
+}
+
+type Set struct {
+	Code          string `json:"code"`
+	Name          string `json:"name"`
+	SetType       string `json:"set_type"`
+	ReleasedAt    string `json:"released_at"`
+	CardCount     int    `json:"card_count"`
+	ParentSetCode string `json:"parent_set_code"`
+	IconSVGURI    string `json:"icon_svg_uri"`
+}
+
+// Migration records Scryfall merging a card object into another, or deleting
+// it, which leaves references to the old ID dangling.
+type Migration struct {
+	PerformedAt       string `json:"performed_at"`
+	MigrationStrategy string `json:"migration_strategy"` // "merge" or "delete"
+	OldScryfallID     string `json:"old_scryfall_id"`
+	NewScryfallID     string `json:"new_scryfall_id"` // Only set for merges
+	Note              string `json:"note"`
+}
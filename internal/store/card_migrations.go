@@ -0,0 +1,230 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// issueOrphaned is the review queue issue for an inventory row whose
+// printing is no longer in the card database.
+const issueOrphaned = "ORPHANED"
+
+// MergeCard moves everything referring to a printing Scryfall merged into
+// another over to the surviving printing, then drops the old one. Stacks
+// that become identical are combined, averaging their purchase prices by
+// quantity. Nothing happens until the new printing has been synced. It
+// returns how many inventory rows were moved.
+func (s *SQLiteStore) MergeCard(oldID, newID string) (int, error) {
+	var referenced, synced bool
+	err := s.db.QueryRow(`
+        SELECT EXISTS(SELECT 1 FROM cards WHERE scryfall_id = ?1) OR EXISTS(SELECT 1 FROM inventory WHERE scryfall_id = ?1),
+               EXISTS(SELECT 1 FROM cards WHERE scryfall_id = ?2)`, oldID, newID).Scan(&referenced, &synced)
+	if err != nil || !referenced || !synced {
+		return 0, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+        SELECT id, quantity, condition, is_foil, language, location, COALESCE(purchase_price, 0)
+        FROM inventory WHERE scryfall_id = ?`, oldID)
+	if err != nil {
+		return 0, err
+	}
+	type stack struct {
+		id, quantity                  int64
+		condition, language, location string
+		isFoil                        bool
+		purchasePrice                 float64
+	}
+	var stacks []stack
+	for rows.Next() {
+		var st stack
+		if err := rows.Scan(&st.id, &st.quantity, &st.condition, &st.isFoil, &st.language, &st.location, &st.purchasePrice); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		stacks = append(stacks, st)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, st := range stacks {
+		var targetID int64
+		err := tx.QueryRow(`
+            SELECT id FROM inventory
+            WHERE scryfall_id = ? AND condition = ? AND is_foil = ? AND language = ? AND location = ?`,
+			newID, st.condition, st.isFoil, st.language, st.location).Scan(&targetID)
+		switch {
+		case err == sql.ErrNoRows:
+			_, err = tx.Exec("UPDATE inventory SET scryfall_id = ? WHERE id = ?", newID, st.id)
+		case err == nil:
+			// Keep imports revertible by pointing their changes at the combined
+			// stack. A price of 0 is unknown, so it does not dilute the average.
+			steps := []struct {
+				query string
+				args  []interface{}
+			}{
+				{`UPDATE inventory SET
+                    purchase_price = CASE
+                        WHEN COALESCE(purchase_price, 0) = 0 THEN ?1
+                        WHEN ?1 = 0 THEN purchase_price
+                        ELSE (purchase_price * quantity + ?1 * ?2) / (quantity + ?2)
+                    END,
+                    quantity = quantity + ?2
+                WHERE id = ?3`, []interface{}{st.purchasePrice, st.quantity, targetID}},
+				{"UPDATE inventory_changes SET inventory_id = ? WHERE inventory_id = ?", []interface{}{targetID, st.id}},
+				{"DELETE FROM inventory WHERE id = ?", []interface{}{st.id}},
+			}
+			for _, step := range steps {
+				if _, err = tx.Exec(step.query, step.args...); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE import_preview SET scryfall_id = ? WHERE scryfall_id = ?", newID, oldID); err != nil {
+		return 0, err
+	}
+	if err := deleteCard(tx, oldID); err != nil {
+		return 0, err
+	}
+
+	return len(stacks), tx.Commit()
+}
+
+// RemoveCard drops a printing Scryfall deleted. Inventory rows of it are
+// moved to the review queue under jobID, so they can be matched to another
+// printing. It returns how many rows were flagged.
+func (s *SQLiteStore) RemoveCard(jobID, id string) (int, error) {
+	var referenced bool
+	err := s.db.QueryRow(`
+        SELECT EXISTS(SELECT 1 FROM cards WHERE scryfall_id = ?1) OR EXISTS(SELECT 1 FROM inventory WHERE scryfall_id = ?1)`,
+		id).Scan(&referenced)
+	if err != nil || !referenced {
+		return 0, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	flagged, err := orphanInventory(tx, jobID, "Scryfall deleted this printing", "i.scryfall_id = ?", id)
+	if err != nil {
+		return 0, err
+	}
+	if err := deleteCard(tx, id); err != nil {
+		return 0, err
+	}
+
+	return flagged, tx.Commit()
+}
+
+// deleteCard removes a printing and its current prices.
+func deleteCard(tx *sql.Tx, id string) error {
+	if _, err := tx.Exec("DELETE FROM card_prices WHERE scryfall_id = ?", id); err != nil {
+		return err
+	}
+	_, err := tx.Exec("DELETE FROM cards WHERE scryfall_id = ?", id)
+	return err
+}
+
+// FlagOrphanedInventory moves every inventory row whose printing is missing
+// from the card database to the review queue under jobID. It returns how
+// many rows were flagged.
+func (s *SQLiteStore) FlagOrphanedInventory(jobID string) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	flagged, err := orphanInventory(tx, jobID, "Printing is not in the card database", "c.scryfall_id IS NULL")
+	if err != nil {
+		return 0, err
+	}
+	return flagged, tx.Commit()
+}
+
+// orphanInventory replaces the inventory rows matching cond (over inventory
+// i joined with cards c) with review items carrying the same details, so
+// resolving one adds the copies back. The import changes recorded against
+// the rows go with them, so reverting those imports cannot touch a new row
+// that reuses an id.
+func orphanInventory(tx *sql.Tx, jobID, reason, cond string, args ...interface{}) (int, error) {
+	rows, err := tx.Query(`
+        SELECT i.id, i.scryfall_id, i.quantity, i.condition, i.is_foil, i.language, COALESCE(i.location, ''), COALESCE(i.purchase_price, 0),
+               COALESCE(c.name, ''), COALESCE(c.set_code, ''), COALESCE(c.collector_number, '')
+        FROM inventory i
+        LEFT JOIN cards c ON i.scryfall_id = c.scryfall_id
+        WHERE `+cond, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	type orphan struct {
+		id       int64
+		raw      map[string]string
+		proposed map[string]interface{}
+	}
+	var orphans []orphan
+	for rows.Next() {
+		var (
+			id                                   int64
+			scryfallID, condition, language, loc string
+			name, set, cn                        string
+			quantity                             int
+			isFoil                               bool
+			purchasePrice                        float64
+		)
+		if err := rows.Scan(&id, &scryfallID, &quantity, &condition, &isFoil, &language, &loc, &purchasePrice, &name, &set, &cn); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		orphans = append(orphans, orphan{
+			id:  id,
+			raw: map[string]string{"scryfall_id": scryfallID, "name": name, "set": set, "cn": cn, "reason": reason},
+			proposed: map[string]interface{}{
+				"quantity":       quantity,
+				"condition":      condition,
+				"is_foil":        isFoil,
+				"language":       language,
+				"location":       loc,
+				"purchase_price": purchasePrice,
+			},
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, o := range orphans {
+		rawBytes, _ := json.Marshal(o.raw)
+		proposedBytes, _ := json.Marshal(o.proposed)
+		if _, err := tx.Exec("INSERT INTO review_queue (job_id, issue_type, raw_data, proposed_values) VALUES (?, ?, ?, ?)",
+			jobID, issueOrphaned, string(rawBytes), string(proposedBytes)); err != nil {
+			return 0, fmt.Errorf("failed to flag inventory %d: %w", o.id, err)
+		}
+		if _, err := tx.Exec("DELETE FROM inventory_changes WHERE inventory_id = ?", o.id); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec("DELETE FROM inventory WHERE id = ?", o.id); err != nil {
+			return 0, err
+		}
+	}
+	return len(orphans), nil
+}
@@ -0,0 +1,261 @@
+package store
+
+import (
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/database"
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+)
+
+// newTestStore opens a fresh database in a temporary directory.
+func newTestStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(database.Close)
+	return NewSQLiteStore(database.DB)
+}
+
+// addCards syncs printings with a USD price each.
+func addCards(t *testing.T, s *SQLiteStore, cards ...models.Card) {
+	t.Helper()
+	for i := range cards {
+		usd := 1.0
+		cards[i].Prices.USD = &usd
+		if cards[i].Lang == "" {
+			cards[i].Lang = "en"
+		}
+	}
+	if err := s.BatchUpsertCards(cards); err != nil {
+		t.Fatalf("BatchUpsertCards: %v", err)
+	}
+}
+
+// importStack adds a stack as row 0 of an import job.
+func importStack(t *testing.T, s *SQLiteStore, jobID string, item models.InventoryItem) int {
+	t.Helper()
+	if err := s.AddJobInventory(jobID, 0, item); err != nil {
+		t.Fatalf("AddJobInventory: %v", err)
+	}
+	var id int
+	err := s.db.QueryRow(`SELECT id FROM inventory WHERE scryfall_id = ? AND condition = ? AND is_foil = ? AND language = ? AND location = ?`,
+		item.ScryfallID, item.Condition, item.IsFoil, item.Language, item.Location).Scan(&id)
+	if err != nil {
+		t.Fatalf("finding stack: %v", err)
+	}
+	return id
+}
+
+func count(t *testing.T, s *SQLiteStore, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := s.db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+func stack(id string, qty int, condition string, price float64) models.InventoryItem {
+	return models.InventoryItem{
+		ScryfallID: id, Quantity: qty, Condition: condition, Language: "en", Location: "Binder", PurchasePrice: price,
+	}
+}
+
+func TestMergeCard(t *testing.T) {
+	s := newTestStore(t)
+	addCards(t, s,
+		models.Card{ScryfallID: "old", Name: "Lightning Bolt", SetCode: "m11", CollectorNumber: "149"},
+		models.Card{ScryfallID: "new", Name: "Lightning Bolt", SetCode: "m11", CollectorNumber: "146"},
+	)
+
+	// The NM copies combine with a stack already on the new printing; the
+	// LP ones have no counterpart and are moved as they are
+	combined := importStack(t, s, "job-a", stack("old", 1, "NM", 2))
+	target := importStack(t, s, "job-b", stack("new", 3, "NM", 4))
+	moved := importStack(t, s, "job-a", stack("old", 2, "LP", 1.25))
+	if err := s.AddPreviewRow("job-c", 0, "MATCHED", "old", nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := s.MergeCard("old", "new")
+	if err != nil {
+		t.Fatalf("MergeCard: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("moved %d stacks, want 2", n)
+	}
+
+	item, err := s.GetInventoryByID(target)
+	if err != nil {
+		t.Fatalf("combined stack: %v", err)
+	}
+	if item.Quantity != 4 {
+		t.Errorf("combined quantity = %d, want 4", item.Quantity)
+	}
+	if want := (1*2.0 + 3*4.0) / 4; math.Abs(item.PurchasePrice-want) > 1e-9 {
+		t.Errorf("combined purchase price = %v, want the weighted %v", item.PurchasePrice, want)
+	}
+	if _, err := s.GetInventoryByID(combined); err == nil {
+		t.Error("the combined-away stack still exists")
+	}
+
+	item, err = s.GetInventoryByID(moved)
+	if err != nil {
+		t.Fatalf("moved stack: %v", err)
+	}
+	if item.ScryfallID != "new" || item.Quantity != 2 || item.PurchasePrice != 1.25 {
+		t.Errorf("moved stack = %s x%d at %v, want new x2 at 1.25", item.ScryfallID, item.Quantity, item.PurchasePrice)
+	}
+
+	// job-a's changes follow its copies, so reverting it still removes them
+	if got := count(t, s, "SELECT COUNT(*) FROM inventory_changes WHERE job_id = 'job-a' AND inventory_id = ?", target); got != 1 {
+		t.Errorf("%d of job-a's changes point at the combined stack, want 1", got)
+	}
+	if got := count(t, s, "SELECT COUNT(*) FROM import_preview WHERE scryfall_id = 'new'"); got != 1 {
+		t.Errorf("preview rows were not moved to the new printing")
+	}
+	if got := count(t, s, "SELECT COUNT(*) FROM cards WHERE scryfall_id = 'old'") +
+		count(t, s, "SELECT COUNT(*) FROM card_prices WHERE scryfall_id = 'old'"); got != 0 {
+		t.Errorf("the old printing or its prices were not removed")
+	}
+
+	completedImport(t, s, "job-a")
+	removed, ok, err := s.RevertJob("job-a")
+	if err != nil || !ok {
+		t.Fatalf("RevertJob = %v, %v", ok, err)
+	}
+	if removed != 3 {
+		t.Errorf("reverting removed %d cards, want 3", removed)
+	}
+	if item, _ := s.GetInventoryByID(target); item == nil || item.Quantity != 3 {
+		t.Errorf("after revert the combined stack should hold job-b's 3 copies")
+	}
+}
+
+func TestMergeCardKeepsKnownPrice(t *testing.T) {
+	s := newTestStore(t)
+	addCards(t, s,
+		models.Card{ScryfallID: "old", Name: "Sol Ring", SetCode: "c21", CollectorNumber: "263"},
+		models.Card{ScryfallID: "new", Name: "Sol Ring", SetCode: "c21", CollectorNumber: "263a"},
+	)
+	importStack(t, s, "job", stack("old", 2, "NM", 3))
+	target := importStack(t, s, "job", stack("new", 1, "NM", 0))
+
+	if _, err := s.MergeCard("old", "new"); err != nil {
+		t.Fatalf("MergeCard: %v", err)
+	}
+	item, err := s.GetInventoryByID(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Quantity != 3 || item.PurchasePrice != 3 {
+		t.Errorf("combined stack = x%d at %v, want x3 at 3 (an unknown price does not count)", item.Quantity, item.PurchasePrice)
+	}
+}
+
+func TestMergeCardWaitsForNewPrinting(t *testing.T) {
+	s := newTestStore(t)
+	addCards(t, s, models.Card{ScryfallID: "old", Name: "Brainstorm", SetCode: "sta", CollectorNumber: "13"})
+	id := importStack(t, s, "job", stack("old", 1, "NM", 0))
+
+	n, err := s.MergeCard("old", "not-synced")
+	if err != nil || n != 0 {
+		t.Fatalf("MergeCard = %d, %v, want nothing done", n, err)
+	}
+	if item, err := s.GetInventoryByID(id); err != nil || item.ScryfallID != "old" {
+		t.Error("inventory moved to a printing that is not in the database")
+	}
+	if got := count(t, s, "SELECT COUNT(*) FROM cards WHERE scryfall_id = 'old'"); got != 1 {
+		t.Error("the old printing was removed")
+	}
+}
+
+func TestRemoveCard(t *testing.T) {
+	s := newTestStore(t)
+	addCards(t, s,
+		models.Card{ScryfallID: "gone", Name: "Delver of Secrets", SetCode: "isd", CollectorNumber: "51"},
+		models.Card{ScryfallID: "kept", Name: "Lightning Bolt", SetCode: "m11", CollectorNumber: "149"},
+	)
+	item := stack("gone", 3, "LP", 0.5)
+	item.IsFoil = true
+	item.Language = "de"
+	orphan := importStack(t, s, "job-a", item)
+	kept := importStack(t, s, "job-a", stack("kept", 1, "NM", 0))
+
+	n, err := s.RemoveCard("sync-job", "gone")
+	if err != nil {
+		t.Fatalf("RemoveCard: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("flagged %d stacks, want 1", n)
+	}
+	if _, err := s.GetInventoryByID(orphan); err == nil {
+		t.Error("the stack of the deleted printing is still in inventory")
+	}
+	if _, err := s.GetInventoryByID(kept); err != nil {
+		t.Error("an unrelated stack was removed")
+	}
+	if got := count(t, s, "SELECT COUNT(*) FROM inventory_changes WHERE inventory_id = ?", orphan); got != 0 {
+		t.Errorf("%d import changes still point at the flagged stack", got)
+	}
+	if got := count(t, s, "SELECT COUNT(*) FROM cards WHERE scryfall_id = 'gone'"); got != 0 {
+		t.Error("the deleted printing is still in the database")
+	}
+
+	items, err := s.ListReviewItems()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("%d review items, want 1", len(items))
+	}
+	review := items[0]
+	if review.JobID != "sync-job" || review.IssueType != issueOrphaned {
+		t.Errorf("review item is %s under job %s, want %s under sync-job", review.IssueType, review.JobID, issueOrphaned)
+	}
+
+	var raw map[string]string
+	json.Unmarshal([]byte(review.RawData), &raw)
+	if raw["name"] != "Delver of Secrets" || raw["set"] != "isd" || raw["cn"] != "51" || raw["scryfall_id"] != "gone" {
+		t.Errorf("raw data = %v, want the printing's details", raw)
+	}
+	var proposed map[string]interface{}
+	json.Unmarshal([]byte(review.ProposedValues), &proposed)
+	if proposed["quantity"] != 3.0 || proposed["condition"] != "LP" || proposed["is_foil"] != true ||
+		proposed["language"] != "de" || proposed["location"] != "Binder" || proposed["purchase_price"] != 0.5 {
+		t.Errorf("proposed values = %v, want the stack's details", proposed)
+	}
+}
+
+func TestFlagOrphanedInventory(t *testing.T) {
+	s := newTestStore(t)
+	addCards(t, s, models.Card{ScryfallID: "kept", Name: "Lightning Bolt", SetCode: "m11", CollectorNumber: "149"})
+	kept := importStack(t, s, "job", stack("kept", 1, "NM", 0))
+	missing := importStack(t, s, "job", stack("never-synced", 2, "NM", 0))
+
+	n, err := s.FlagOrphanedInventory("sync-job")
+	if err != nil {
+		t.Fatalf("FlagOrphanedInventory: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("flagged %d stacks, want 1", n)
+	}
+	if _, err := s.GetInventoryByID(missing); err == nil {
+		t.Error("the stack without a printing is still in inventory")
+	}
+	if _, err := s.GetInventoryByID(kept); err != nil {
+		t.Error("a stack with a printing was flagged")
+	}
+	if got := count(t, s, "SELECT COUNT(*) FROM review_queue WHERE issue_type = ? AND job_id = 'sync-job'", issueOrphaned); got != 1 {
+		t.Errorf("%d review items, want 1", got)
+	}
+
+	// Nothing is left to flag the second time
+	if n, err := s.FlagOrphanedInventory("sync-job"); err != nil || n != 0 {
+		t.Errorf("second pass flagged %d, %v", n, err)
+	}
+}
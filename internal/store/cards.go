@@ -3,6 +3,7 @@ package store
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/JulianDominic/GatheringTheBulk/internal/models"
 )
@@ -11,30 +12,54 @@ type CardSearchResult struct {
 	ScryfallID      string `json:"scryfall_id"`
 	Name            string `json:"name"`
 	SetCode         string `json:"set_code"`
+	SetName         string `json:"set_name"`
 	CollectorNumber string `json:"collector_number"`
 	ImageURI        string `json:"image_uri"`
+	Lang            string `json:"lang"`
 	Label           string `json:"label"` // Helper for UI
 }
 
+// label names the printing, and its language when it is not English, since
+// an all_cards sync holds the same printing in several languages.
+func (c *CardSearchResult) label() string {
+	label := fmt.Sprintf("%s (%s #%s)", c.Name, c.SetCode, c.CollectorNumber)
+	if c.Lang != "" && c.Lang != "en" {
+		label += " [" + strings.ToUpper(c.Lang) + "]"
+	}
+	return label
+}
+
 // SearchCards performs a prefix/fuzzy search on card names.
-func (s *SQLiteStore) SearchCards(query, preferredSet string) ([]CardSearchResult, error) {
+func (s *SQLiteStore) SearchCards(query, preferredSet, lang string) ([]CardSearchResult, error) {
 	if query == "" {
 		return nil, nil // Or empty list
 	}
 
-	// Simple LIKE search with ordering preference
+	// Simple LIKE search with ordering preference, newest printings first.
+	// An all_cards sync holds a printing once per language, so each is listed
+	// once: in lang if it was printed in it, else in English.
 	sqlQuery := `
-        SELECT scryfall_id, name, set_code, collector_number, image_uri
-        FROM cards
-        WHERE name LIKE ?
+        SELECT c.scryfall_id, c.name, c.set_code, COALESCE(st.name, c.set_name, ''), c.collector_number, c.image_uri, c.lang
+        FROM (
+            SELECT scryfall_id, name, set_code, set_name, collector_number, image_uri, COALESCE(lang, 'en') AS lang,
+                   ROW_NUMBER() OVER (
+                       PARTITION BY set_code, collector_number
+                       ORDER BY CASE COALESCE(lang, 'en') WHEN ? THEN 0 WHEN 'en' THEN 1 ELSE 2 END, lang
+                   ) AS version
+            FROM cards
+            WHERE name LIKE ?
+        ) c
+        LEFT JOIN sets st ON st.code = c.set_code
+        WHERE c.version = 1
         ORDER BY 
-            CASE WHEN LOWER(set_code) = LOWER(?) THEN 0 ELSE 1 END,
-            name ASC, 
-            set_code DESC
+            CASE WHEN LOWER(c.set_code) = LOWER(?) THEN 0 ELSE 1 END,
+            c.name ASC, 
+            st.released_at DESC,
+            c.set_code DESC
         LIMIT 20
     `
 	q := "%" + query + "%"
-	rows, err := s.db.Query(sqlQuery, q, preferredSet)
+	rows, err := s.db.Query(sqlQuery, lang, q, preferredSet)
 	if err != nil {
 		return nil, err
 	}
@@ -43,10 +68,10 @@ func (s *SQLiteStore) SearchCards(query, preferredSet string) ([]CardSearchResul
 	var results []CardSearchResult
 	for rows.Next() {
 		var c CardSearchResult
-		if err := rows.Scan(&c.ScryfallID, &c.Name, &c.SetCode, &c.CollectorNumber, &c.ImageURI); err != nil {
+		if err := rows.Scan(&c.ScryfallID, &c.Name, &c.SetCode, &c.SetName, &c.CollectorNumber, &c.ImageURI, &c.Lang); err != nil {
 			return nil, err
 		}
-		c.Label = fmt.Sprintf("%s (%s #%s)", c.Name, c.SetCode, c.CollectorNumber)
+		c.Label = c.label()
 		results = append(results, c)
 	}
 	return results, nil
@@ -54,31 +79,41 @@ func (s *SQLiteStore) SearchCards(query, preferredSet string) ([]CardSearchResul
 
 func (s *SQLiteStore) GetCardByScryfallID(id string) (*CardSearchResult, error) {
 	query := `
-        SELECT scryfall_id, name, set_code, collector_number, image_uri
+        SELECT scryfall_id, name, set_code, collector_number, image_uri, COALESCE(lang, 'en')
         FROM cards
         WHERE scryfall_id = ?
     `
 	var c CardSearchResult
-	err := s.db.QueryRow(query, id).Scan(&c.ScryfallID, &c.Name, &c.SetCode, &c.CollectorNumber, &c.ImageURI)
+	err := s.db.QueryRow(query, id).Scan(&c.ScryfallID, &c.Name, &c.SetCode, &c.CollectorNumber, &c.ImageURI, &c.Lang)
 	if err != nil {
 		return nil, err
 	}
-	c.Label = fmt.Sprintf("%s (%s #%s)", c.Name, c.SetCode, c.CollectorNumber)
+	c.Label = c.label()
 	return &c, nil
 }
 
-func (s *SQLiteStore) FindCardBySetCN(set, cn string) (string, error) {
+// FindCardBySetCN returns the printing with a set code and collector
+// number, in lang if the database holds that language, else in English.
+func (s *SQLiteStore) FindCardBySetCN(set, cn, lang string) (string, error) {
 	var id string
-	err := s.db.QueryRow("SELECT scryfall_id FROM cards WHERE LOWER(set_code) = ? AND collector_number = ?", strings.ToLower(set), cn).Scan(&id)
+	err := s.db.QueryRow(`
+        SELECT scryfall_id FROM cards
+        WHERE LOWER(set_code) = ? AND collector_number = ?
+        ORDER BY CASE COALESCE(lang, 'en') WHEN ? THEN 0 WHEN 'en' THEN 1 ELSE 2 END
+        LIMIT 1`, strings.ToLower(set), cn, lang).Scan(&id)
 	if err != nil {
 		return "", fmt.Errorf("not found")
 	}
 	return id, nil
 }
 
-func (s *SQLiteStore) FindSmartCard(name, set string) (string, error) {
-	query := "SELECT scryfall_id FROM cards WHERE LOWER(name) = ?"
-	args := []interface{}{strings.ToLower(name)}
+// FindSmartCard returns the single printing with a name, optionally within
+// a set. Names printed on foreign cards match too. When the name exists in
+// several languages only the printings in lang count, or failing that the
+// English ones, so a card is not ambiguous merely for being translated.
+func (s *SQLiteStore) FindSmartCard(name, set, lang string) (string, error) {
+	query := "SELECT scryfall_id, COALESCE(lang, 'en') FROM cards WHERE (LOWER(name) = ? OR LOWER(printed_name) = ?)"
+	args := []interface{}{strings.ToLower(name), strings.ToLower(name)}
 
 	if set != "" {
 		query += " AND LOWER(set_code) = ?"
@@ -91,18 +126,28 @@ func (s *SQLiteStore) FindSmartCard(name, set string) (string, error) {
 	}
 	defer rows.Close()
 
-	var ids []string
+	byLang := make(map[string][]string)
 	for rows.Next() {
-		var id string
-		if err := rows.Scan(&id); err == nil {
-			ids = append(ids, id)
+		var id, cardLang string
+		if err := rows.Scan(&id, &cardLang); err == nil {
+			byLang[cardLang] = append(byLang[cardLang], id)
 		}
 	}
 
-	if len(ids) == 0 {
+	if len(byLang) == 0 {
 		return "", fmt.Errorf("not found")
 	}
 
+	ids := byLang[lang]
+	if len(ids) == 0 {
+		ids = byLang["en"]
+	}
+	if len(ids) == 0 {
+		for _, langIDs := range byLang {
+			ids = append(ids, langIDs...)
+		}
+	}
+
 	if len(ids) == 1 {
 		return ids[0], nil // Perfect match
 	}
@@ -110,13 +155,63 @@ func (s *SQLiteStore) FindSmartCard(name, set string) (string, error) {
 	return "", fmt.Errorf("ambiguous: %d matches", len(ids))
 }
 
+// ListCardImages returns the image URL of every card with one, or only of
+// the cards in the inventory.
+func (s *SQLiteStore) ListCardImages(ownedOnly bool) ([]models.CardImage, error) {
+	query := "SELECT scryfall_id, image_uri FROM cards WHERE image_uri != ''"
+	if ownedOnly {
+		query += " AND scryfall_id IN (SELECT scryfall_id FROM inventory)"
+	}
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []models.CardImage
+	for rows.Next() {
+		var img models.CardImage
+		if err := rows.Scan(&img.ScryfallID, &img.ImageURI); err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	return images, rows.Err()
+}
+
+// ListCardNames returns every distinct card name, for fuzzy matching.
+func (s *SQLiteStore) ListCardNames() ([]string, error) {
+	rows, err := s.db.Query("SELECT DISTINCT name FROM cards")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// BatchUpsertCards writes cards and their current prices, and records a
+// price_history snapshot dated today for every card whose prices differ from
+// its latest earlier snapshot.
 func (s *SQLiteStore) BatchUpsertCards(cards []models.Card) error {
+	today := time.Now().Format("2006-01-02")
+
 	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
 
-	query := `INSERT OR REPLACE INTO cards (scryfall_id, name, set_code, collector_number, image_uri) VALUES (?, ?, ?, ?, ?)`
+	query := `INSERT OR REPLACE INTO cards (scryfall_id, name, set_code, collector_number, image_uri, set_name, layout, mtgo_id,
+                  oracle_id, lang, printed_name, released_at, rarity, type_line, mana_cost, cmc, colors, color_identity, finishes)
+              VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, 0), ?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?, ?)`
 	stmt, err := tx.Prepare(query)
 	if err != nil {
 		tx.Rollback()
@@ -124,8 +219,58 @@ func (s *SQLiteStore) BatchUpsertCards(cards []models.Card) error {
 	}
 	defer stmt.Close()
 
+	priceStmt, err := tx.Prepare(`INSERT OR REPLACE INTO card_prices (scryfall_id, usd, usd_foil, usd_etched, eur, eur_foil, tix, updated_at)
+              VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer priceStmt.Close()
+
+	// A second sync on the same day replaces that day's snapshot, so it is
+	// removed before comparing against the snapshot before it
+	clearStmt, err := tx.Prepare("DELETE FROM price_history WHERE scryfall_id = ? AND date = ?")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer clearStmt.Close()
+
+	historyStmt, err := tx.Prepare(`INSERT INTO price_history (scryfall_id, date, usd, usd_foil, usd_etched, eur, eur_foil, tix)
+              SELECT ?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8
+              WHERE NOT EXISTS (
+                  SELECT 1 FROM (
+                      SELECT usd, usd_foil, usd_etched, eur, eur_foil, tix FROM price_history
+                      WHERE scryfall_id = ?1 ORDER BY date DESC LIMIT 1
+                  ) l
+                  WHERE l.usd IS ?3 AND l.usd_foil IS ?4 AND l.usd_etched IS ?5
+                    AND l.eur IS ?6 AND l.eur_foil IS ?7 AND l.tix IS ?8
+              )`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer historyStmt.Close()
+
 	for _, c := range cards {
-		_, err = stmt.Exec(c.ScryfallID, c.Name, c.SetCode, c.CollectorNumber, c.ImageURI)
+		_, err = stmt.Exec(c.ScryfallID, c.Name, c.SetCode, c.CollectorNumber, c.ImageURI, c.SetName, c.Layout, c.MTGOID,
+			c.OracleID, c.Lang, c.PrintedName, c.ReleasedAt, c.Rarity, c.TypeLine, c.ManaCost, c.CMC, c.Colors, c.ColorIdentity, c.Finishes)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		p := c.Prices
+		_, err = priceStmt.Exec(c.ScryfallID, p.USD, p.USDFoil, p.USDEtched, p.EUR, p.EURFoil, p.Tix)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err = clearStmt.Exec(c.ScryfallID, today); err != nil {
+			tx.Rollback()
+			return err
+		}
+		_, err = historyStmt.Exec(c.ScryfallID, today, p.USD, p.USDFoil, p.USDEtched, p.EUR, p.EURFoil, p.Tix)
 		if err != nil {
 			tx.Rollback()
 			return err
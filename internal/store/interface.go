@@ -9,40 +9,95 @@ import (
 // Store defines all data access operations
 type Store interface {
 	// Inventory
-	ListInventory(limit, offset int, searchQuery string) ([]models.InventoryItem, int, error)
+	ListInventory(limit, offset int, searchQuery, sort string) ([]models.InventoryItem, int, error)
+	ListInventoryGroups(limit, offset int, searchQuery, sort string) ([]models.InventoryGroup, int, error)
+	ListInventoryStacks(oracleID, searchQuery string) ([]models.InventoryItem, error)
+	InventoryValue(searchQuery string) (float64, int, error)
+	EachInventory(searchQuery string, fn func(models.InventoryItem) error) error
+	ListDeckCards(ids []int, searchQuery string) ([]models.DeckCard, error)
 	AddInventory(item models.InventoryItem) error
+	AddJobInventory(jobID string, rowIndex int, item models.InventoryItem) error
 	UpdateInventory(item models.InventoryItem) error
 	DeleteInventory(id int) error
 	GetInventoryByID(id int) (*models.InventoryItem, error)
 	SearchInventoryNames(query string) ([]string, error)
+	ListLocations() ([]string, error)
 
 	// Cards
-	SearchCards(query, preferredSet string) ([]CardSearchResult, error)
+	SearchCards(query, preferredSet, lang string) ([]CardSearchResult, error)
 	GetCardByScryfallID(id string) (*CardSearchResult, error)
-	FindCardBySetCN(set, cn string) (string, error)
-	FindSmartCard(name, set string) (string, error)
+	FindCardBySetCN(set, cn, lang string) (string, error)
+	FindSmartCard(name, set, lang string) (string, error)
+	ListCardNames() ([]string, error)
+	ListCardImages(ownedOnly bool) ([]models.CardImage, error)
 	BatchUpsertCards(cards []models.Card) error
+	MergeCard(oldID, newID string) (int, error)
+	RemoveCard(jobID, id string) (int, error)
+	FlagOrphanedInventory(jobID string) (int, error)
+
+	// Sets
+	UpsertSets(sets []models.Set) error
+	ListMissingSetIcons() ([]string, error)
+	SaveSetIcon(uri, svg string) error
+	GetSetIcon(code string) (string, error)
+	ListInventorySets() ([]models.Set, error)
+
+	// Collection Value
+	RecordCollectionValue(p models.ValuePoint) error
+	CollectionSize() (int, error)
+	ListCollectionValues(since string) ([]models.ValuePoint, error)
+	ListPriceMovers(since string, limit int) ([]models.PriceMover, error)
 
 	// Jobs
 	CreateJob(job *models.Job) error
 	GetJob(id string) (*models.Job, error)
 	UpdateJobStatus(id string, status models.JobStatus) error
+	SwapJobStatus(id string, from, status models.JobStatus) (bool, error)
 	UpdateJobProgress(id string, current, total int) error
 	CompleteJob(id string, summary string) error
 	FailJob(id string, errorMsg string) error
+	ListJobs(limit int) ([]models.Job, error)
+	ListUnfinishedJobs() ([]models.Job, error)
+	JobCommittedRows(id string) (map[int]bool, error)
+	RevertJob(id string) (int, bool, error)
 
 	// Review
-	AddReviewItem(jobID, issueType string, rawData map[string]string, proposedValues map[string]interface{}) error
+	AddReviewItem(jobID string, rowIndex int, issueType string, rawData map[string]string, proposedValues map[string]interface{}, candidates []models.MatchCandidate) error
 	ListReviewItems() ([]models.ReviewItem, error)
 	GetReviewItem(id int) (*models.ReviewItem, error)
 	DeleteReviewItem(id int) error
 	CountReviewItems() (int, error)
 
+	// Import Preview
+	AddPreviewRow(jobID string, rowIndex int, status, scryfallID string, rawData map[string]string, proposedValues map[string]interface{}, candidates []models.MatchCandidate) error
+	ListPreviewRows(jobID string) ([]models.PreviewRow, error)
+	DeletePreviewRows(jobID string) error
+
+	// Import Profiles
+	SaveImportProfile(name string, mapping map[string]string) error
+	ListImportProfiles() ([]models.ImportProfile, error)
+	GetImportProfile(id int) (*models.ImportProfile, error)
+	DeleteImportProfile(id int) error
+
 	// Settings
 	GetSetting(key string) (string, error)
 	SetSetting(key, value string) error
 }
 
+// queryer is satisfied by both *sql.DB and *sql.Tx, so helpers can run
+// inside or outside a transaction.
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// scanner is satisfied by both *sql.Row and *sql.Rows, so one function can
+// read a row whether the query returns one or many.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
 type SQLiteStore struct {
 	db *sql.DB
 }
@@ -1,37 +1,77 @@
 package store
 
 import (
+	"database/sql"
+	"fmt"
+	"strings"
+
 	"github.com/JulianDominic/GatheringTheBulk/internal/models"
 )
 
-// ListInventory returns paged inventory items joined with card data.
-func (s *SQLiteStore) ListInventory(limit, offset int, searchQuery string) ([]models.InventoryItem, int, error) {
+// unitPrice is the USD price of one copy of an inventory row (i) in its
+// finish, from a table of prices with the given alias. Foil rows of
+// etched-only printings use the etched price.
+func unitPrice(alias string) string {
+	return fmt.Sprintf("CASE WHEN i.is_foil THEN COALESCE(%[1]s.usd_foil, %[1]s.usd_etched) ELSE %[1]s.usd END", alias)
+}
+
+// unitPriceSQL is unitPrice from card_prices (p).
+var unitPriceSQL = unitPrice("p")
+
+// inventorySorts maps the dashboard sort options to ORDER BY clauses.
+var inventorySorts = map[string]string{
+	"":      "i.id DESC", // Last added
+	"name":  "c.name, c.set_code, c.collector_number, i.id",
+	"set":   "st.released_at DESC, c.set_code, CAST(c.collector_number AS INTEGER), c.collector_number, i.id",
+	"value": "COALESCE(" + unitPriceSQL + ", 0) * i.quantity DESC, i.id DESC",
+}
+
+// inventoryItemSQL selects inventory rows joined with their card, set and
+// price, in the columns scanInventoryItem reads. Callers append the WHERE
+// and ORDER BY clauses.
+var inventoryItemSQL = `
+        SELECT i.id, i.scryfall_id, i.quantity, i.condition, i.is_foil, i.language, COALESCE(i.location, ''), COALESCE(i.purchase_price, 0),
+               COALESCE(c.name, ''), COALESCE(c.set_code, ''), COALESCE(c.collector_number, ''), COALESCE(c.image_uri, ''),
+               COALESCE(st.name, c.set_name, ''), ` + unitPriceSQL + `
+        FROM inventory i
+        LEFT JOIN cards c ON i.scryfall_id = c.scryfall_id
+        LEFT JOIN card_prices p ON i.scryfall_id = p.scryfall_id
+        LEFT JOIN sets st ON st.code = c.set_code
+    `
+
+// scanInventoryItem reads a row selected by inventoryItemSQL.
+func scanInventoryItem(row scanner) (models.InventoryItem, error) {
+	var item models.InventoryItem
+	var price sql.NullFloat64
+	err := row.Scan(
+		&item.ID, &item.ScryfallID, &item.Quantity, &item.Condition, &item.IsFoil, &item.Language, &item.Location, &item.PurchasePrice,
+		&item.CardName, &item.SetCode, &item.CollectorNumber, &item.ImageURI, &item.SetName, &price,
+	)
+	item.Price, item.Priced = price.Float64, price.Valid
+	return item, err
+}
+
+// ListInventory returns paged inventory items joined with card data and
+// prices, ordered by one of inventorySorts.
+func (s *SQLiteStore) ListInventory(limit, offset int, searchQuery, sort string) ([]models.InventoryItem, int, error) {
 	var total int
 
 	// Base count query
-	countQuery := "SELECT COUNT(*) FROM inventory i LEFT JOIN cards c ON i.scryfall_id = c.scryfall_id"
-	var args []interface{}
-
-	if searchQuery != "" {
-		countQuery += " WHERE c.name LIKE ?"
-		args = append(args, "%"+searchQuery+"%")
-	}
+	where, args := inventoryFilter(searchQuery)
+	countQuery := "SELECT COUNT(*) FROM inventory i LEFT JOIN cards c ON i.scryfall_id = c.scryfall_id" + where
 
 	if err := s.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
-	query := `
-        SELECT i.id, i.scryfall_id, i.quantity, i.condition, i.is_foil, i.language, i.location,
-               c.name, c.set_code, c.collector_number, c.image_uri
-        FROM inventory i
-        LEFT JOIN cards c ON i.scryfall_id = c.scryfall_id
-    `
-	if searchQuery != "" {
-		query += " WHERE c.name LIKE ?" // Args already prepared above
+	orderBy, ok := inventorySorts[sort]
+	if !ok {
+		orderBy = inventorySorts[""]
 	}
 
-	query += " ORDER BY i.id DESC LIMIT ? OFFSET ?" // Sorted by Last Added
+	query := inventoryItemSQL + where // Args already prepared above
+
+	query += " ORDER BY " + orderBy + " LIMIT ? OFFSET ?"
 	args = append(args, limit, offset)
 
 	rows, err := s.db.Query(query, args...)
@@ -42,11 +82,8 @@ func (s *SQLiteStore) ListInventory(limit, offset int, searchQuery string) ([]mo
 
 	var items []models.InventoryItem
 	for rows.Next() {
-		var item models.InventoryItem
-		if err := rows.Scan(
-			&item.ID, &item.ScryfallID, &item.Quantity, &item.Condition, &item.IsFoil, &item.Language, &item.Location,
-			&item.CardName, &item.SetCode, &item.CollectorNumber, &item.ImageURI,
-		); err != nil {
+		item, err := scanInventoryItem(rows)
+		if err != nil {
 			return nil, 0, err
 		}
 		items = append(items, item)
@@ -54,41 +91,296 @@ func (s *SQLiteStore) ListInventory(limit, offset int, searchQuery string) ([]mo
 	return items, total, nil
 }
 
+// oracleKeySQL groups inventory rows by card rather than printing. Cards
+// synced before oracle IDs were stored fall back to their own printing.
+const oracleKeySQL = "COALESCE(c.oracle_id, i.scryfall_id)"
+
+// inventoryGroupSorts maps the dashboard sort options to ORDER BY clauses
+// over the grouped view.
+var inventoryGroupSorts = map[string]string{
+	"":      "g.last_id DESC",
+	"name":  "g.name, g.oracle_key",
+	"set":   "g.released_at DESC, g.name",
+	"value": "g.value DESC, g.last_id DESC",
+}
+
+// ListInventoryGroups returns paged inventory totals per card, whatever the
+// printing or language, ordered by one of inventoryGroupSorts.
+func (s *SQLiteStore) ListInventoryGroups(limit, offset int, searchQuery, sort string) ([]models.InventoryGroup, int, error) {
+	where, args := inventoryFilter(searchQuery)
+
+	var total int
+	countQuery := "SELECT COUNT(DISTINCT " + oracleKeySQL + ") FROM inventory i LEFT JOIN cards c ON i.scryfall_id = c.scryfall_id" + where
+	if err := s.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	orderBy, ok := inventoryGroupSorts[sort]
+	if !ok {
+		orderBy = inventoryGroupSorts[""]
+	}
+
+	query := `
+        SELECT g.oracle_key, g.name, (SELECT scryfall_id FROM inventory WHERE id = g.last_id),
+               g.quantity, g.printings, g.stacks, g.value, g.unpriced
+        FROM (
+            SELECT ` + oracleKeySQL + ` AS oracle_key, COALESCE(MIN(c.name), '') AS name, MAX(i.id) AS last_id,
+                   SUM(i.quantity) AS quantity, COUNT(DISTINCT i.scryfall_id) AS printings, COUNT(*) AS stacks,
+                   COALESCE(SUM(` + unitPriceSQL + ` * i.quantity), 0) AS value,
+                   SUM(CASE WHEN ` + unitPriceSQL + ` IS NULL THEN i.quantity ELSE 0 END) AS unpriced,
+                   MAX(st.released_at) AS released_at
+            FROM inventory i
+            LEFT JOIN cards c ON i.scryfall_id = c.scryfall_id
+            LEFT JOIN card_prices p ON i.scryfall_id = p.scryfall_id
+            LEFT JOIN sets st ON st.code = c.set_code
+    ` + where + `
+            GROUP BY oracle_key
+        ) g
+        ORDER BY ` + orderBy + " LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var groups []models.InventoryGroup
+	for rows.Next() {
+		var g models.InventoryGroup
+		if err := rows.Scan(&g.OracleID, &g.Name, &g.ScryfallID, &g.Quantity, &g.Printings, &g.Stacks, &g.Value, &g.Unpriced); err != nil {
+			return nil, 0, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, total, rows.Err()
+}
+
+// ListInventoryStacks returns the inventory rows of one card group from
+// ListInventoryGroups, limited to the same dashboard search, ordered by
+// printing then location.
+func (s *SQLiteStore) ListInventoryStacks(oracleID, searchQuery string) ([]models.InventoryItem, error) {
+	where, args := inventoryFilter(searchQuery)
+	if where == "" {
+		where = " WHERE "
+	} else {
+		where += " AND "
+	}
+	where += oracleKeySQL + " = ?"
+	args = append(args, oracleID)
+
+	query := inventoryItemSQL + where + `
+        ORDER BY st.released_at DESC, c.set_code, c.collector_number, i.language, i.location, i.id`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.InventoryItem
+	for rows.Next() {
+		item, err := scanInventoryItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// InventoryValue returns the current USD value of the inventory matching the
+// dashboard search, and how many of its cards have no known price.
+func (s *SQLiteStore) InventoryValue(searchQuery string) (float64, int, error) {
+	where, args := inventoryFilter(searchQuery)
+	query := `
+        SELECT COALESCE(SUM(` + unitPriceSQL + ` * i.quantity), 0),
+               COALESCE(SUM(CASE WHEN ` + unitPriceSQL + ` IS NULL THEN i.quantity ELSE 0 END), 0)
+        FROM inventory i
+        LEFT JOIN cards c ON i.scryfall_id = c.scryfall_id
+        LEFT JOIN card_prices p ON i.scryfall_id = p.scryfall_id
+    ` + where
+
+	var value float64
+	var unpriced int
+	if err := s.db.QueryRow(query, args...).Scan(&value, &unpriced); err != nil {
+		return 0, 0, err
+	}
+	return value, unpriced, nil
+}
+
+// inventoryFilter returns the WHERE clause and arguments for a dashboard
+// search over inventory joined with cards. Words of the form "set:mh2" limit
+// the search to a set; the rest must appear in the card name.
+func inventoryFilter(searchQuery string) (string, []interface{}) {
+	var conds, words []string
+	var args []interface{}
+	for _, word := range strings.Fields(searchQuery) {
+		if code, ok := strings.CutPrefix(strings.ToLower(word), "set:"); ok && code != "" {
+			conds = append(conds, "LOWER(c.set_code) = ?")
+			args = append(args, code)
+			continue
+		}
+		words = append(words, word)
+	}
+	if len(words) > 0 {
+		conds = append(conds, "c.name LIKE ?")
+		args = append(args, "%"+strings.Join(words, " ")+"%")
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// EachInventory streams every inventory item matching the dashboard search
+// to fn, ordered by card, without loading the collection into memory.
+// Iteration stops at the first error fn returns.
+func (s *SQLiteStore) EachInventory(searchQuery string, fn func(models.InventoryItem) error) error {
+	where, args := inventoryFilter(searchQuery)
+	query := inventoryItemSQL + where + " ORDER BY c.name, c.set_code, c.collector_number, i.id"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := scanInventoryItem(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ListDeckCards aggregates inventory by printing for a decklist export. The
+// given inventory IDs are used if there are any, otherwise the dashboard
+// search.
+func (s *SQLiteStore) ListDeckCards(ids []int, searchQuery string) ([]models.DeckCard, error) {
+	where, args := inventoryFilter(searchQuery)
+	if len(ids) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+		where = " WHERE i.id IN (" + placeholders + ")"
+		args = nil
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+
+	query := `
+        SELECT i.scryfall_id, c.name, c.set_code, c.collector_number, COALESCE(c.layout, ''), COALESCE(c.mtgo_id, 0),
+               SUM(i.quantity)
+        FROM inventory i
+        JOIN cards c ON i.scryfall_id = c.scryfall_id
+    ` + where + `
+        GROUP BY i.scryfall_id
+        ORDER BY c.name, c.set_code
+    `
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cards []models.DeckCard
+	for rows.Next() {
+		var c models.DeckCard
+		if err := rows.Scan(&c.ScryfallID, &c.Name, &c.SetCode, &c.CollectorNumber, &c.Layout, &c.MTGOID, &c.Quantity); err != nil {
+			return nil, err
+		}
+		cards = append(cards, c)
+	}
+	return cards, rows.Err()
+}
+
 func (s *SQLiteStore) AddInventory(item models.InventoryItem) error {
+	_, err := addInventory(s.db, item)
+	return err
+}
+
+// AddJobInventory adds an item on behalf of an import job and records the
+// quantity it contributed, so that RevertJob can subtract exactly that much.
+// rowIndex is the source row of the import, or -1 if there is none.
+func (s *SQLiteStore) AddJobInventory(jobID string, rowIndex int, item models.InventoryItem) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	id, err := addInventory(tx, item)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.Exec("INSERT INTO inventory_changes (job_id, inventory_id, quantity, row_index) VALUES (?, ?, ?, ?)", jobID, id, item.Quantity, rowIndex); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// addInventory merges the item into an identical stack if one exists, or
+// inserts a new row. It returns the ID of the row that was changed.
+func addInventory(q queryer, item models.InventoryItem) (int64, error) {
 	// Check for existing item to merge quantities
-	var existingID int
+	var existingID int64
 	var existingQty int
-	err := s.db.QueryRow(`
+	err := q.QueryRow(`
         SELECT id, quantity FROM inventory 
-        WHERE scryfall_id = ? AND condition = ? AND is_foil = ? AND language = ?
-    `, item.ScryfallID, item.Condition, item.IsFoil, item.Language).Scan(&existingID, &existingQty)
+        WHERE scryfall_id = ? AND condition = ? AND is_foil = ? AND language = ? AND location = ?
+    `, item.ScryfallID, item.Condition, item.IsFoil, item.Language, item.Location).Scan(&existingID, &existingQty)
 
 	if err == nil {
 		// Item exists, update quantity
-		_, err = s.db.Exec("UPDATE inventory SET quantity = ? WHERE id = ?", existingQty+item.Quantity, existingID)
-		return err
+		_, err = q.Exec("UPDATE inventory SET quantity = ? WHERE id = ?", existingQty+item.Quantity, existingID)
+		return existingID, err
 	}
 
 	// Item does not exist, insert new
-	_, err = s.db.Exec(`
-        INSERT INTO inventory (scryfall_id, quantity, condition, is_foil, language, location)
-        VALUES (?, ?, ?, ?, ?, ?)
-    `, item.ScryfallID, item.Quantity, item.Condition, item.IsFoil, item.Language, item.Location)
-	return err
+	res, err := q.Exec(`
+        INSERT INTO inventory (scryfall_id, quantity, condition, is_foil, language, location, purchase_price)
+        VALUES (?, ?, ?, ?, ?, ?, ?)
+    `, item.ScryfallID, item.Quantity, item.Condition, item.IsFoil, item.Language, item.Location, item.PurchasePrice)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
 }
 
 func (s *SQLiteStore) UpdateInventory(item models.InventoryItem) error {
 	_, err := s.db.Exec(`
         UPDATE inventory 
-        SET quantity=?, condition=?, is_foil=?, language=?, location=?
+        SET quantity=?, condition=?, is_foil=?, language=?, location=?, purchase_price=?
         WHERE id=?
-    `, item.Quantity, item.Condition, item.IsFoil, item.Language, item.Location, item.ID)
+    `, item.Quantity, item.Condition, item.IsFoil, item.Language, item.Location, item.PurchasePrice, item.ID)
 	return err
 }
 
+// DeleteInventory removes a stack along with the import changes recorded
+// against it, so reverting those imports later cannot touch a new row that
+// reuses the id.
 func (s *SQLiteStore) DeleteInventory(id int) error {
-	_, err := s.db.Exec("DELETE FROM inventory WHERE id = ?", id)
-	return err
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM inventory_changes WHERE inventory_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM inventory WHERE id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (s *SQLiteStore) SearchInventoryNames(query string) ([]string, error) {
@@ -114,19 +406,30 @@ func (s *SQLiteStore) SearchInventoryNames(query string) ([]string, error) {
 	return names, nil
 }
 
+// ListLocations returns the distinct locations cards are stored in.
+func (s *SQLiteStore) ListLocations() ([]string, error) {
+	rows, err := s.db.Query(`
+        SELECT DISTINCT location FROM inventory
+        WHERE location IS NOT NULL AND location != ''
+        ORDER BY location ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locations []string
+	for rows.Next() {
+		var location string
+		if err := rows.Scan(&location); err != nil {
+			continue
+		}
+		locations = append(locations, location)
+	}
+	return locations, nil
+}
+
 func (s *SQLiteStore) GetInventoryByID(id int) (*models.InventoryItem, error) {
-	query := `
-        SELECT i.id, i.scryfall_id, i.quantity, i.condition, i.is_foil, i.language, i.location,
-               c.name, c.set_code, c.collector_number, c.image_uri
-        FROM inventory i
-        LEFT JOIN cards c ON i.scryfall_id = c.scryfall_id
-        WHERE i.id = ?
-    `
-	var item models.InventoryItem
-	err := s.db.QueryRow(query, id).Scan(
-		&item.ID, &item.ScryfallID, &item.Quantity, &item.Condition, &item.IsFoil, &item.Language, &item.Location,
-		&item.CardName, &item.SetCode, &item.CollectorNumber, &item.ImageURI,
-	)
+	item, err := scanInventoryItem(s.db.QueryRow(inventoryItemSQL+" WHERE i.id = ?", id))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,37 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+)
+
+func TestEachInventorySetNames(t *testing.T) {
+	s := newTestStore(t)
+	addCards(t, s,
+		models.Card{ScryfallID: "bolt", Name: "Lightning Bolt", SetCode: "m11", SetName: "M11", CollectorNumber: "149"},
+		models.Card{ScryfallID: "ring", Name: "Sol Ring", SetCode: "c21", SetName: "Commander 2021", CollectorNumber: "263"},
+	)
+	if err := s.UpsertSets([]models.Set{{Code: "m11", Name: "Magic 2011"}}); err != nil {
+		t.Fatal(err)
+	}
+	importStack(t, s, "job", stack("bolt", 1, "NM", 0))
+	importStack(t, s, "job", stack("ring", 1, "NM", 0))
+
+	// The synced set list wins over the name stored with the card, as on the
+	// dashboard
+	want := map[string]string{"bolt": "Magic 2011", "ring": "Commander 2021"}
+	err := s.EachInventory("", func(item models.InventoryItem) error {
+		if item.SetName != want[item.ScryfallID] {
+			t.Errorf("%s set name = %q, want %q", item.ScryfallID, item.SetName, want[item.ScryfallID])
+		}
+		delete(want, item.ScryfallID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachInventory: %v", err)
+	}
+	if len(want) != 0 {
+		t.Errorf("not exported: %v", want)
+	}
+}
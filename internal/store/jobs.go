@@ -7,35 +7,32 @@ import (
 )
 
 func (s *SQLiteStore) CreateJob(job *models.Job) error {
-	query := `INSERT INTO jobs (id, type, status, created_at) VALUES (?, ?, ?, ?)`
-	_, err := s.db.Exec(query, job.ID, job.Type, job.Status, job.CreatedAt)
+	query := `INSERT INTO jobs (id, type, status, created_at, params) VALUES (?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, job.ID, job.Type, job.Status, job.CreatedAt, job.Params)
 	return err
 }
 
-func (s *SQLiteStore) GetJob(id string) (*models.Job, error) {
-	query := `SELECT id, type, status, progress_current, progress_total, result_summary, created_at FROM jobs WHERE id = ?`
-	row := s.db.QueryRow(query, id)
+// jobColumns are the jobs columns scanJob reads, in order.
+const jobColumns = "id, type, status, progress_current, progress_total, result_summary, created_at, params"
 
+// scanJob reads a row of jobColumns.
+func scanJob(row scanner) (models.Job, error) {
 	var job models.Job
-	var resultSummary sql.NullString
-
+	var resultSummary, params sql.NullString
 	err := row.Scan(
-		&job.ID,
-		&job.Type,
-		&job.Status,
-		&job.ProgressCurrent,
-		&job.ProgressTotal,
-		&resultSummary,
-		&job.CreatedAt,
+		&job.ID, &job.Type, &job.Status, &job.ProgressCurrent, &job.ProgressTotal,
+		&resultSummary, &job.CreatedAt, &params,
 	)
+	job.ResultSummary = resultSummary.String
+	job.Params = params.String
+	return job, err
+}
+
+func (s *SQLiteStore) GetJob(id string) (*models.Job, error) {
+	job, err := scanJob(s.db.QueryRow("SELECT "+jobColumns+" FROM jobs WHERE id = ?", id))
 	if err != nil {
 		return nil, err
 	}
-
-	if resultSummary.Valid {
-		job.ResultSummary = resultSummary.String
-	}
-
 	return &job, nil
 }
 
@@ -44,6 +41,17 @@ func (s *SQLiteStore) UpdateJobStatus(id string, status models.JobStatus) error
 	return err
 }
 
+// SwapJobStatus moves a job to status only if it is still in from. It
+// reports whether it did, so two requests cannot both act on the same job.
+func (s *SQLiteStore) SwapJobStatus(id string, from, status models.JobStatus) (bool, error) {
+	res, err := s.db.Exec("UPDATE jobs SET status = ? WHERE id = ? AND status = ?", status, id, from)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
 func (s *SQLiteStore) UpdateJobProgress(id string, current, total int) error {
 	_, err := s.db.Exec("UPDATE jobs SET progress_current = ?, progress_total = ? WHERE id = ?", current, total, id)
 	return err
@@ -59,3 +67,125 @@ func (s *SQLiteStore) FailJob(id string, errorMsg string) error {
 	_, err := s.db.Exec("UPDATE jobs SET status = ?, result_summary = ? WHERE id = ?", models.JobStatusFailed, errorMsg, id)
 	return err
 }
+
+// ListJobs returns the most recent jobs, newest first.
+func (s *SQLiteStore) ListJobs(limit int) ([]models.Job, error) {
+	query := "SELECT " + jobColumns + " FROM jobs ORDER BY created_at DESC LIMIT ?"
+	rows, err := s.db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []models.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// ListUnfinishedJobs returns jobs left PENDING or PROCESSING, oldest first.
+func (s *SQLiteStore) ListUnfinishedJobs() ([]models.Job, error) {
+	query := "SELECT " + jobColumns + " FROM jobs WHERE status IN (?, ?) ORDER BY created_at ASC"
+	rows, err := s.db.Query(query, models.JobStatusPending, models.JobStatusProcessing)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []models.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// JobCommittedRows returns the source rows of an import that have already
+// been written, mapped to true if the row was added to inventory and false
+// if it was sent to the review queue.
+func (s *SQLiteStore) JobCommittedRows(id string) (map[int]bool, error) {
+	rows, err := s.db.Query(`
+        SELECT row_index, 0 FROM review_queue WHERE job_id = ? AND row_index >= 0
+        UNION ALL
+        SELECT row_index, 1 FROM inventory_changes WHERE job_id = ? AND row_index >= 0
+    `, id, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := make(map[int]bool)
+	for rows.Next() {
+		var idx int
+		var added bool
+		if err := rows.Scan(&idx, &added); err != nil {
+			return nil, err
+		}
+		done[idx] = done[idx] || added
+	}
+	return done, nil
+}
+
+// RevertJob undoes an import: it subtracts every quantity the job added to
+// inventory (deleting stacks that reach zero), clears the job's review and
+// preview rows and marks the job REVERTED. A stack the user has since edited
+// down loses at most what it still holds. Only a COMPLETED job is reverted,
+// claimed in the same transaction so a concurrent commit or revert cannot
+// act on it too; the bool reports whether it was. It returns the number of
+// cards removed from inventory.
+func (s *SQLiteStore) RevertJob(id string) (int, bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE jobs SET status = ? WHERE id = ? AND status = ?",
+		models.JobStatusReverted, id, models.JobStatusCompleted)
+	if err != nil {
+		return 0, false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return 0, false, err
+	}
+
+	var removed int
+	if err := tx.QueryRow(`
+        SELECT COALESCE(SUM(MIN(i.quantity, ch.added)), 0)
+        FROM inventory i
+        JOIN (SELECT inventory_id, SUM(quantity) AS added FROM inventory_changes WHERE job_id = ? GROUP BY inventory_id) ch
+            ON ch.inventory_id = i.id`, id).Scan(&removed); err != nil {
+		return 0, false, err
+	}
+
+	steps := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`UPDATE inventory SET quantity = MAX(quantity - (
+            SELECT SUM(ch.quantity) FROM inventory_changes ch WHERE ch.inventory_id = inventory.id AND ch.job_id = ?
+        ), 0) WHERE id IN (SELECT inventory_id FROM inventory_changes WHERE job_id = ?)`, []interface{}{id, id}},
+		{`DELETE FROM inventory WHERE quantity <= 0 AND id IN (SELECT inventory_id FROM inventory_changes WHERE job_id = ?)`, []interface{}{id}},
+		// Other imports' changes to the stacks just deleted go with them
+		{`DELETE FROM inventory_changes WHERE inventory_id IN (SELECT inventory_id FROM inventory_changes WHERE job_id = ?)
+            AND inventory_id NOT IN (SELECT id FROM inventory)`, []interface{}{id}},
+		{`DELETE FROM inventory_changes WHERE job_id = ?`, []interface{}{id}},
+		{`DELETE FROM review_queue WHERE job_id = ?`, []interface{}{id}},
+		{`DELETE FROM import_preview WHERE job_id = ?`, []interface{}{id}},
+	}
+	for _, step := range steps {
+		if _, err := tx.Exec(step.query, step.args...); err != nil {
+			return 0, false, err
+		}
+	}
+
+	return removed, true, tx.Commit()
+}
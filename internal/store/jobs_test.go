@@ -0,0 +1,91 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+)
+
+// completedImport records a finished CSV import job with the given id.
+func completedImport(t *testing.T, s *SQLiteStore, id string) {
+	t.Helper()
+	job := &models.Job{ID: id, Type: models.JobTypeCSVImport, Status: models.JobStatusPending, CreatedAt: time.Now()}
+	if err := s.CreateJob(job); err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+	if err := s.CompleteJob(id, `{"imported":1}`); err != nil {
+		t.Fatalf("CompleteJob: %v", err)
+	}
+}
+
+func TestRevertJobAfterMerge(t *testing.T) {
+	s := newTestStore(t)
+	addCards(t, s, models.Card{ScryfallID: "bolt", Name: "Lightning Bolt", SetCode: "m11", CollectorNumber: "149"})
+
+	// Both imports add to the same stack, which the user then edits down
+	// below what the second one added
+	completedImport(t, s, "job-a")
+	completedImport(t, s, "job-b")
+	id := importStack(t, s, "job-a", stack("bolt", 2, "NM", 0))
+	if got := importStack(t, s, "job-b", stack("bolt", 4, "NM", 0)); got != id {
+		t.Fatalf("job-b made stack %d, want it merged into %d", got, id)
+	}
+	item, err := s.GetInventoryByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item.Quantity = 3
+	if err := s.UpdateInventory(*item); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, ok, err := s.RevertJob("job-b")
+	if err != nil || !ok {
+		t.Fatalf("RevertJob = %v, %v", ok, err)
+	}
+	if removed != 3 {
+		t.Errorf("removed %d cards, want the 3 left in the stack", removed)
+	}
+	if _, err := s.GetInventoryByID(id); err == nil {
+		t.Error("the emptied stack still exists")
+	}
+	if got := count(t, s, "SELECT COUNT(*) FROM inventory_changes"); got != 0 {
+		t.Errorf("%d import changes still point at the deleted stack", got)
+	}
+	if job, _ := s.GetJob("job-b"); job == nil || job.Status != models.JobStatusReverted {
+		t.Error("job-b was not marked reverted")
+	}
+
+	// job-a's copies went with the stack, so there is nothing left to undo
+	removed, ok, err = s.RevertJob("job-a")
+	if err != nil || !ok || removed != 0 {
+		t.Errorf("reverting job-a = %d, %v, %v, want 0 removed", removed, ok, err)
+	}
+}
+
+func TestRevertJobOnlyOnce(t *testing.T) {
+	s := newTestStore(t)
+	addCards(t, s, models.Card{ScryfallID: "bolt", Name: "Lightning Bolt", SetCode: "m11", CollectorNumber: "149"})
+	completedImport(t, s, "job")
+	id := importStack(t, s, "job", stack("bolt", 2, "NM", 0))
+
+	// A confirm import has claimed the job first
+	if ok, err := s.SwapJobStatus("job", models.JobStatusCompleted, models.JobStatusPending); err != nil || !ok {
+		t.Fatalf("SwapJobStatus = %v, %v", ok, err)
+	}
+	if _, ok, err := s.RevertJob("job"); err != nil || ok {
+		t.Fatalf("RevertJob of a pending job = %v, %v, want it refused", ok, err)
+	}
+	if item, err := s.GetInventoryByID(id); err != nil || item.Quantity != 2 {
+		t.Error("a refused revert changed inventory")
+	}
+
+	s.UpdateJobStatus("job", models.JobStatusCompleted)
+	if _, ok, err := s.RevertJob("job"); err != nil || !ok {
+		t.Fatalf("RevertJob = %v, %v", ok, err)
+	}
+	if _, ok, err := s.RevertJob("job"); err != nil || ok {
+		t.Errorf("second RevertJob = %v, %v, want it refused", ok, err)
+	}
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"encoding/json"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+)
+
+// AddPreviewRow stages the outcome of one row of a dry-run import.
+func (s *SQLiteStore) AddPreviewRow(jobID string, rowIndex int, status, scryfallID string, rawData map[string]string, proposedValues map[string]interface{}, candidates []models.MatchCandidate) error {
+	rawBytes, _ := json.Marshal(rawData)
+	proposedBytes, _ := json.Marshal(proposedValues)
+
+	query := `INSERT INTO import_preview (job_id, status, scryfall_id, raw_data, proposed_values, row_index, candidates) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, jobID, status, scryfallID, string(rawBytes), string(proposedBytes), rowIndex, candidatesJSON(candidates))
+	return err
+}
+
+// ListPreviewRows returns the staged rows of a job joined with card data, in
+// the order they were imported.
+func (s *SQLiteStore) ListPreviewRows(jobID string) ([]models.PreviewRow, error) {
+	query := `
+        SELECT p.id, p.job_id, COALESCE(p.row_index, -1), p.status, COALESCE(p.scryfall_id, ''), p.raw_data, p.proposed_values, COALESCE(p.candidates, ''),
+               COALESCE(c.name, ''), COALESCE(c.set_code, ''), COALESCE(c.collector_number, '')
+        FROM import_preview p
+        LEFT JOIN cards c ON p.scryfall_id = c.scryfall_id
+        WHERE p.job_id = ?
+        ORDER BY p.id ASC
+    `
+	rows, err := s.db.Query(query, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.PreviewRow
+	for rows.Next() {
+		var item models.PreviewRow
+		if err := rows.Scan(
+			&item.ID, &item.JobID, &item.RowIndex, &item.Status, &item.ScryfallID, &item.RawData, &item.ProposedValues, &item.Candidates,
+			&item.CardName, &item.SetCode, &item.CollectorNumber,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// DeletePreviewRows discards all staged rows of a job.
+func (s *SQLiteStore) DeletePreviewRows(jobID string) error {
+	_, err := s.db.Exec("DELETE FROM import_preview WHERE job_id = ?", jobID)
+	return err
+}
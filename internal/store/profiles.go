@@ -0,0 +1,57 @@
+package store
+
+import (
+	"encoding/json"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+)
+
+// SaveImportProfile stores a column mapping under a name, replacing any
+// profile of the same name.
+func (s *SQLiteStore) SaveImportProfile(name string, mapping map[string]string) error {
+	mappingBytes, _ := json.Marshal(mapping)
+
+	query := `INSERT INTO import_profiles (name, mapping) VALUES (?, ?)
+              ON CONFLICT(name) DO UPDATE SET mapping = excluded.mapping`
+	_, err := s.db.Exec(query, name, string(mappingBytes))
+	return err
+}
+
+// ListImportProfiles returns all saved profiles ordered by name.
+func (s *SQLiteStore) ListImportProfiles() ([]models.ImportProfile, error) {
+	rows, err := s.db.Query("SELECT id, name, mapping FROM import_profiles ORDER BY name ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []models.ImportProfile
+	for rows.Next() {
+		var p models.ImportProfile
+		var mapping string
+		if err := rows.Scan(&p.ID, &p.Name, &mapping); err != nil {
+			return nil, err
+		}
+		json.Unmarshal([]byte(mapping), &p.Mapping)
+		profiles = append(profiles, p)
+	}
+	return profiles, nil
+}
+
+// GetImportProfile retrieves a single profile by ID.
+func (s *SQLiteStore) GetImportProfile(id int) (*models.ImportProfile, error) {
+	var p models.ImportProfile
+	var mapping string
+	err := s.db.QueryRow("SELECT id, name, mapping FROM import_profiles WHERE id = ?", id).Scan(&p.ID, &p.Name, &mapping)
+	if err != nil {
+		return nil, err
+	}
+	json.Unmarshal([]byte(mapping), &p.Mapping)
+	return &p, nil
+}
+
+// DeleteImportProfile removes a saved profile.
+func (s *SQLiteStore) DeleteImportProfile(id int) error {
+	_, err := s.db.Exec("DELETE FROM import_profiles WHERE id = ?", id)
+	return err
+}
@@ -7,18 +7,28 @@ import (
 )
 
 // AddReviewItem inserts a record into review_queue.
-func (s *SQLiteStore) AddReviewItem(jobID, issueType string, rawData map[string]string, proposedValues map[string]interface{}) error {
+// candidates holds the fuzzy match suggestions for the row, if any.
+func (s *SQLiteStore) AddReviewItem(jobID string, rowIndex int, issueType string, rawData map[string]string, proposedValues map[string]interface{}, candidates []models.MatchCandidate) error {
 	rawBytes, _ := json.Marshal(rawData)
 	proposedBytes, _ := json.Marshal(proposedValues)
 
-	query := `INSERT INTO review_queue (job_id, issue_type, raw_data, proposed_values) VALUES (?, ?, ?, ?)`
-	_, err := s.db.Exec(query, jobID, issueType, string(rawBytes), string(proposedBytes))
+	query := `INSERT INTO review_queue (job_id, issue_type, raw_data, proposed_values, row_index, candidates) VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, jobID, issueType, string(rawBytes), string(proposedBytes), rowIndex, candidatesJSON(candidates))
 	return err
 }
 
+// candidatesJSON encodes match suggestions, storing NULL when there are none.
+func candidatesJSON(candidates []models.MatchCandidate) interface{} {
+	if len(candidates) == 0 {
+		return nil
+	}
+	b, _ := json.Marshal(candidates)
+	return string(b)
+}
+
 // ListReviewItems returns all pending review items.
 func (s *SQLiteStore) ListReviewItems() ([]models.ReviewItem, error) {
-	query := `SELECT id, job_id, issue_type, raw_data, proposed_values FROM review_queue ORDER BY id ASC`
+	query := `SELECT id, job_id, COALESCE(row_index, -1), issue_type, raw_data, proposed_values, COALESCE(candidates, '') FROM review_queue ORDER BY id ASC`
 	rows, err := s.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -28,7 +38,7 @@ func (s *SQLiteStore) ListReviewItems() ([]models.ReviewItem, error) {
 	var items []models.ReviewItem
 	for rows.Next() {
 		var item models.ReviewItem
-		if err := rows.Scan(&item.ID, &item.JobID, &item.IssueType, &item.RawData, &item.ProposedValues); err != nil {
+		if err := rows.Scan(&item.ID, &item.JobID, &item.RowIndex, &item.IssueType, &item.RawData, &item.ProposedValues, &item.Candidates); err != nil {
 			return nil, err
 		}
 		items = append(items, item)
@@ -38,9 +48,9 @@ func (s *SQLiteStore) ListReviewItems() ([]models.ReviewItem, error) {
 
 // GetReviewItem retrieves a single item by ID.
 func (s *SQLiteStore) GetReviewItem(id int) (*models.ReviewItem, error) {
-	query := `SELECT id, job_id, issue_type, raw_data, proposed_values FROM review_queue WHERE id = ?`
+	query := `SELECT id, job_id, COALESCE(row_index, -1), issue_type, raw_data, proposed_values, COALESCE(candidates, '') FROM review_queue WHERE id = ?`
 	var item models.ReviewItem
-	err := s.db.QueryRow(query, id).Scan(&item.ID, &item.JobID, &item.IssueType, &item.RawData, &item.ProposedValues)
+	err := s.db.QueryRow(query, id).Scan(&item.ID, &item.JobID, &item.RowIndex, &item.IssueType, &item.RawData, &item.ProposedValues, &item.Candidates)
 	if err != nil {
 		return nil, err
 	}
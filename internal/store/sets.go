@@ -0,0 +1,106 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+)
+
+// UpsertSets writes the set list. A set's cached icon is kept unless its
+// icon URI changed.
+func (s *SQLiteStore) UpsertSets(sets []models.Set) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+        INSERT INTO sets (code, name, set_type, released_at, card_count, parent_set_code, icon_svg_uri)
+        VALUES (?, ?, ?, ?, ?, ?, ?)
+        ON CONFLICT(code) DO UPDATE SET
+            name = excluded.name,
+            set_type = excluded.set_type,
+            released_at = excluded.released_at,
+            card_count = excluded.card_count,
+            parent_set_code = excluded.parent_set_code,
+            icon_svg = CASE WHEN sets.icon_svg_uri = excluded.icon_svg_uri THEN sets.icon_svg END,
+            icon_svg_uri = excluded.icon_svg_uri
+    `)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, set := range sets {
+		_, err := stmt.Exec(set.Code, set.Name, set.SetType, set.ReleasedAt, set.CardCount, set.ParentSetCode, set.IconSVGURI)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListMissingSetIcons returns the icon URIs that have not been downloaded
+// yet. Many sets share an icon, so each URI is listed once.
+func (s *SQLiteStore) ListMissingSetIcons() ([]string, error) {
+	rows, err := s.db.Query("SELECT DISTINCT icon_svg_uri FROM sets WHERE icon_svg IS NULL AND icon_svg_uri != ''")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uris []string
+	for rows.Next() {
+		var uri string
+		if err := rows.Scan(&uri); err != nil {
+			return nil, err
+		}
+		uris = append(uris, uri)
+	}
+	return uris, rows.Err()
+}
+
+// SaveSetIcon caches an icon for every set using it.
+func (s *SQLiteStore) SaveSetIcon(uri, svg string) error {
+	_, err := s.db.Exec("UPDATE sets SET icon_svg = ? WHERE icon_svg_uri = ?", svg, uri)
+	return err
+}
+
+// GetSetIcon returns a set's cached icon SVG, or "" if there is none.
+func (s *SQLiteStore) GetSetIcon(code string) (string, error) {
+	var svg sql.NullString
+	err := s.db.QueryRow("SELECT icon_svg FROM sets WHERE code = LOWER(?)", code).Scan(&svg)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return svg.String, err
+}
+
+// ListInventorySets returns the sets the inventory holds cards from, newest
+// first.
+func (s *SQLiteStore) ListInventorySets() ([]models.Set, error) {
+	rows, err := s.db.Query(`
+        SELECT c.set_code, COALESCE(st.name, c.set_name, c.set_code), COALESCE(st.set_type, ''), COALESCE(st.released_at, '')
+        FROM (SELECT DISTINCT scryfall_id FROM inventory) i
+        JOIN cards c ON i.scryfall_id = c.scryfall_id
+        LEFT JOIN sets st ON st.code = c.set_code
+        GROUP BY c.set_code
+        ORDER BY st.released_at DESC, c.set_code
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sets []models.Set
+	for rows.Next() {
+		var set models.Set
+		if err := rows.Scan(&set.Code, &set.Name, &set.SetType, &set.ReleasedAt); err != nil {
+			return nil, err
+		}
+		sets = append(sets, set)
+	}
+	return sets, rows.Err()
+}
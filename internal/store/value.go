@@ -0,0 +1,81 @@
+package store
+
+import (
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+)
+
+// RecordCollectionValue stores the collection's value for a day, replacing
+// any value already recorded for it.
+func (s *SQLiteStore) RecordCollectionValue(p models.ValuePoint) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO collection_value (date, usd, cards, unpriced, recorded_at)
+                         VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)`, p.Date, p.USD, p.Cards, p.Unpriced)
+	return err
+}
+
+// CollectionSize returns how many copies the inventory holds.
+func (s *SQLiteStore) CollectionSize() (int, error) {
+	var n int
+	err := s.db.QueryRow("SELECT COALESCE(SUM(quantity), 0) FROM inventory").Scan(&n)
+	return n, err
+}
+
+// ListCollectionValues returns the recorded values from the given date
+// onwards, oldest first. An empty date returns all of them.
+func (s *SQLiteStore) ListCollectionValues(since string) ([]models.ValuePoint, error) {
+	rows, err := s.db.Query("SELECT date, usd, cards, unpriced FROM collection_value WHERE date >= ? ORDER BY date", since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []models.ValuePoint
+	for rows.Next() {
+		var p models.ValuePoint
+		if err := rows.Scan(&p.Date, &p.USD, &p.Cards, &p.Unpriced); err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// ListPriceMovers compares the current price of each owned printing and
+// finish with its price on the given date, and returns those whose owned
+// copies changed most in value, largest change first. A printing first
+// priced after that date is compared with its earliest snapshot; printings
+// without a price on either side are left out.
+func (s *SQLiteStore) ListPriceMovers(since string, limit int) ([]models.PriceMover, error) {
+	query := `
+        SELECT scryfall_id, name, set_code, collector_number, is_foil, quantity, old_price, price
+        FROM (
+            SELECT i.scryfall_id, c.name, c.set_code, c.collector_number, i.is_foil, SUM(i.quantity) AS quantity,
+                   ` + unitPriceSQL + ` AS price,
+                   (SELECT ` + unitPrice("h") + ` FROM price_history h
+                    WHERE h.scryfall_id = i.scryfall_id
+                    ORDER BY h.date > ?1, CASE WHEN h.date <= ?1 THEN h.date END DESC, h.date
+                    LIMIT 1) AS old_price
+            FROM inventory i
+            JOIN cards c ON i.scryfall_id = c.scryfall_id
+            JOIN card_prices p ON i.scryfall_id = p.scryfall_id
+            GROUP BY i.scryfall_id, i.is_foil
+        )
+        WHERE price IS NOT NULL AND old_price IS NOT NULL AND price != old_price
+        ORDER BY ABS(price - old_price) * quantity DESC
+        LIMIT ?2
+    `
+	rows, err := s.db.Query(query, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movers []models.PriceMover
+	for rows.Next() {
+		var m models.PriceMover
+		if err := rows.Scan(&m.ScryfallID, &m.Name, &m.SetCode, &m.CollectorNumber, &m.IsFoil, &m.Quantity, &m.OldPrice, &m.Price); err != nil {
+			return nil, err
+		}
+		movers = append(movers, m)
+	}
+	return movers, rows.Err()
+}
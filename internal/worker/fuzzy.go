@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"log"
+	"strconv"
+	"sync"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/matcher"
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+	"github.com/JulianDominic/GatheringTheBulk/internal/store"
+)
+
+// maxCandidates is how many suggestions are kept for a review item.
+const maxCandidates = 5
+
+// fuzzyMatcher suggests card names for rows whose name did not match. The
+// name index is only built once an import actually needs it.
+type fuzzyMatcher struct {
+	store     store.Store
+	threshold float64 // Auto-accept score, 0 to never auto-accept
+
+	once  sync.Once
+	index *matcher.Index
+}
+
+func newFuzzyMatcher(s store.Store) *fuzzyMatcher {
+	percent := matcher.DefaultThreshold
+	if v, err := s.GetSetting(matcher.ThresholdSetting); err == nil && v != "" {
+		if p, err := strconv.Atoi(v); err == nil {
+			percent = p
+		}
+	}
+	return &fuzzyMatcher{store: s, threshold: float64(percent) / 100}
+}
+
+// candidates returns the card names closest to name, best first.
+func (f *fuzzyMatcher) candidates(name string) []models.MatchCandidate {
+	f.once.Do(func() {
+		names, err := f.store.ListCardNames()
+		if err != nil {
+			log.Printf("Failed to load card names for fuzzy matching: %v", err)
+		}
+		f.index = matcher.NewIndex(names)
+	})
+	return f.index.Match(name, maxCandidates)
+}
+
+// accept returns the candidate confident enough to use without review. The
+// best candidate must clear the threshold and beat the runner-up outright.
+func (f *fuzzyMatcher) accept(candidates []models.MatchCandidate) (string, bool) {
+	if f.threshold <= 0 || len(candidates) == 0 || candidates[0].Score < f.threshold {
+		return "", false
+	}
+	if len(candidates) > 1 && candidates[1].Score >= candidates[0].Score {
+		return "", false
+	}
+	return candidates[0].Name, true
+}
@@ -0,0 +1,310 @@
+package worker
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+)
+
+// Fields understood by the import pipeline. Every import format maps its
+// own column names onto these.
+const (
+	fieldName      = "name"
+	fieldSet       = "set"
+	fieldCN        = "cn"
+	fieldQuantity  = "quantity"
+	fieldCondition = "condition"
+	fieldFoil      = "foil"
+	fieldLanguage  = "language"
+	fieldLocation  = "location"
+	fieldPrice     = "price"
+	fieldScryfall  = "scryfall_id"
+)
+
+// ImportField is a field a CSV column can be mapped onto by the user.
+type ImportField struct {
+	Key   string
+	Label string
+}
+
+// ImportFields lists the mappable fields in the order they are offered.
+var ImportFields = []ImportField{
+	{fieldName, "Card Name"},
+	{fieldSet, "Set Code"},
+	{fieldCN, "Collector Number"},
+	{fieldQuantity, "Quantity"},
+	{fieldCondition, "Condition"},
+	{fieldFoil, "Foil"},
+	{fieldLanguage, "Language"},
+	{fieldLocation, "Location"},
+	{fieldPrice, "Purchase Price"},
+	{fieldScryfall, "Scryfall ID"},
+}
+
+// importFormat describes the column layout of a CSV export from a given tool.
+type importFormat struct {
+	Name string
+
+	// Signature lists headers (lower-cased) that must all be present for the
+	// format to be selected. An empty signature matches any header row.
+	Signature []string
+
+	// Columns maps each field to the headers (lower-cased) it may appear under,
+	// in order of preference.
+	Columns map[string][]string
+}
+
+// importFormats is checked in order; the first format whose signature is
+// satisfied by the header row wins. The native format must stay last as it
+// acts as the fallback.
+var importFormats = []importFormat{
+	{
+		Name:      "Dragon Shield",
+		Signature: []string{"card name", "set code", "card number", "printing"},
+		Columns: map[string][]string{
+			fieldName:      {"card name"},
+			fieldSet:       {"set code"},
+			fieldCN:        {"card number"},
+			fieldQuantity:  {"quantity"},
+			fieldCondition: {"condition"},
+			fieldFoil:      {"printing"},
+			fieldLanguage:  {"language"},
+			fieldLocation:  {"folder name"},
+		},
+	},
+	{
+		Name:      "ManaBox",
+		Signature: []string{"name", "set code", "collector number"},
+		Columns: map[string][]string{
+			fieldName:      {"name"},
+			fieldSet:       {"set code"},
+			fieldCN:        {"collector number"},
+			fieldQuantity:  {"quantity"},
+			fieldCondition: {"condition"},
+			fieldFoil:      {"foil"},
+			fieldLanguage:  {"language"},
+			fieldLocation:  {"binder name"},
+			fieldScryfall:  {"scryfall id"},
+		},
+	},
+	{
+		Name:      "Moxfield",
+		Signature: []string{"count", "name", "edition", "last modified"},
+		Columns: map[string][]string{
+			fieldName:      {"name"},
+			fieldSet:       {"edition"},
+			fieldCN:        {"collector number"},
+			fieldQuantity:  {"count"},
+			fieldCondition: {"condition"},
+			fieldFoil:      {"foil"},
+			fieldLanguage:  {"language"},
+		},
+	},
+	{
+		// Deckbox's "Edition" column holds the full set name, so only the
+		// code column (present in newer exports) is used for the set.
+		Name:      "Deckbox",
+		Signature: []string{"count", "name", "edition", "card number"},
+		Columns: map[string][]string{
+			fieldName:      {"name"},
+			fieldSet:       {"edition code"},
+			fieldCN:        {"card number"},
+			fieldQuantity:  {"count"},
+			fieldCondition: {"condition"},
+			fieldFoil:      {"foil"},
+			fieldLanguage:  {"language"},
+		},
+	},
+	{
+		Name: "GatheringTheBulk",
+		Columns: map[string][]string{
+			fieldName:      {"name"},
+			fieldSet:       {"set"},
+			fieldCN:        {"collector_number", "cn"},
+			fieldQuantity:  {"quantity", "qty"},
+			fieldCondition: {"condition"},
+			fieldFoil:      {"foil"},
+			fieldLanguage:  {"language"},
+			fieldLocation:  {"location"},
+			fieldPrice:     {"purchase_price", "price"},
+			fieldScryfall:  {"scryfall_id"},
+		},
+	},
+}
+
+// normalizeHeader lower-cases and trims a header cell, dropping the UTF-8 BOM
+// that spreadsheet tools like to prepend to the first column.
+func normalizeHeader(h string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+}
+
+// detectFormat picks the import format matching the header row and resolves
+// each of its fields to a column index. Fields without a column are omitted.
+func detectFormat(header []string) (*importFormat, map[string]int) {
+	present := headerIndex(header)
+
+	for i := range importFormats {
+		f := &importFormats[i]
+		matched := true
+		for _, sig := range f.Signature {
+			if _, ok := present[sig]; !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return f, f.columns(present)
+		}
+	}
+	return nil, nil // Unreachable while the native format is registered last
+}
+
+// mappedFormat builds a format from a user-defined field -> header mapping.
+func mappedFormat(name string, mapping map[string]string) *importFormat {
+	if name == "" {
+		name = "Custom Mapping"
+	}
+	f := &importFormat{Name: name, Columns: make(map[string][]string)}
+	for field, header := range mapping {
+		if header != "" {
+			f.Columns[field] = []string{normalizeHeader(header)}
+		}
+	}
+	return f
+}
+
+// columns resolves each field of the format to a column index.
+func (f *importFormat) columns(present map[string]int) map[string]int {
+	cols := make(map[string]int)
+	for field, aliases := range f.Columns {
+		for _, alias := range aliases {
+			if idx, ok := present[alias]; ok {
+				cols[field] = idx
+				break
+			}
+		}
+	}
+	return cols
+}
+
+// headerIndex maps each normalised header to its first column index.
+func headerIndex(header []string) map[string]int {
+	present := make(map[string]int)
+	for i, h := range header {
+		key := normalizeHeader(h)
+		if _, dup := present[key]; !dup {
+			present[key] = i
+		}
+	}
+	return present
+}
+
+// canMatch reports whether the available fields are enough to identify a
+// card: a name, a set code plus collector number, or a Scryfall ID.
+func canMatch(has func(field string) bool) bool {
+	return has(fieldName) || (has(fieldSet) && has(fieldCN)) || has(fieldScryfall)
+}
+
+// MappingUsable reports whether a user-defined mapping can identify cards.
+func MappingUsable(mapping map[string]string) bool {
+	return canMatch(func(field string) bool { return mapping[field] != "" })
+}
+
+// MatchProfile returns the first saved profile whose mapped headers are all
+// present in the header row, or nil.
+func MatchProfile(profiles []models.ImportProfile, header []string) *models.ImportProfile {
+	present := headerIndex(header)
+	for i := range profiles {
+		p := &profiles[i]
+		matched := len(p.Mapping) > 0
+		for _, h := range p.Mapping {
+			if _, ok := present[normalizeHeader(h)]; !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return p
+		}
+	}
+	return nil
+}
+
+// CSVInspection describes an uploaded CSV before it is imported, so the user
+// can be asked to map its columns.
+type CSVInspection struct {
+	Header  []string
+	Samples [][]string
+	Format  string            // Name of the detected format
+	Fields  map[string]string // Field -> header, as detected
+	Usable  bool              // The detected columns can identify cards
+}
+
+// InspectCSV reads the header row and up to n sample rows of a CSV file and
+// runs format detection on it.
+func InspectCSV(path string, n int) (*CSVInspection, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+
+	header, err := readCSVHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	format, cols := detectFormat(header)
+	ins := &CSVInspection{
+		Header: header,
+		Format: format.Name,
+		Fields: make(map[string]string),
+	}
+	for field, idx := range cols {
+		ins.Fields[field] = header[idx]
+	}
+	ins.Usable = canMatch(func(field string) bool { _, ok := cols[field]; return ok })
+
+	for len(ins.Samples) < n {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			continue
+		}
+		ins.Samples = append(ins.Samples, row)
+	}
+	return ins, nil
+}
+
+// readCSVHeader returns the header row, skipping an Excel "sep=" hint line.
+func readCSVHeader(reader *csv.Reader) ([]string, error) {
+	header, err := reader.Read()
+	if err == nil && isSeparatorHint(header) {
+		header, err = reader.Read()
+	}
+	return header, err
+}
+
+// isSeparatorHint reports whether a row is the Excel "sep=," hint line some
+// tools (e.g. Dragon Shield) write before the real header.
+func isSeparatorHint(row []string) bool {
+	return len(row) > 0 && strings.HasPrefix(normalizeHeader(row[0]), "sep=")
+}
+
+// parseFoil understands the foil vocabularies of the supported formats:
+// "true", "foil", "etched" or a Printing column value of "Foil".
+func parseFoil(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "true", "yes", "y", "1", "foil", "etched":
+		return true
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package worker
 
 import (
 	"log"
+	"sync"
 
 	"github.com/JulianDominic/GatheringTheBulk/internal/models"
 	"github.com/JulianDominic/GatheringTheBulk/internal/store"
@@ -15,6 +16,10 @@ type JobRequest struct {
 type Dispatcher struct {
 	store    store.Store
 	jobQueue chan JobRequest
+
+	// paused is held for reading while a job runs and for writing while the
+	// database is being replaced, so neither happens during the other.
+	paused sync.RWMutex
 }
 
 func NewDispatcher(s store.Store, buffer int) *Dispatcher {
@@ -35,23 +40,44 @@ func (d *Dispatcher) QueueJob(req JobRequest) {
 	d.jobQueue <- req
 }
 
+// Pause stops jobs from starting until resume is called. It fails if a job
+// is running, rather than waiting for it.
+func (d *Dispatcher) Pause() (resume func(), ok bool) {
+	if !d.paused.TryLock() {
+		return nil, false
+	}
+	return d.paused.Unlock, true
+}
+
 func (d *Dispatcher) worker(id int) {
 	for req := range d.jobQueue {
-		log.Printf("[Worker %d] Starting Job %s (%s)", id, req.Job.ID, req.Job.Type)
-
-		if err := d.store.UpdateJobStatus(req.Job.ID, models.JobStatusProcessing); err != nil {
-			log.Printf("Failed to update status for job %s: %v", req.Job.ID, err)
-			continue
-		}
-
-		summary, err := req.Handler(d.store, req.Job)
-
-		if err != nil {
-			log.Printf("[Worker %d] Job %s Failed: %v", id, req.Job.ID, err)
-			d.store.FailJob(req.Job.ID, err.Error())
-		} else {
-			log.Printf("[Worker %d] Job %s Completed", id, req.Job.ID)
-			d.store.CompleteJob(req.Job.ID, summary)
-		}
+		d.paused.RLock()
+		d.run(id, req)
+		d.paused.RUnlock()
+	}
+}
+
+func (d *Dispatcher) run(id int, req JobRequest) {
+	// A job queued while the database was restored is not in the new one
+	if _, err := d.store.GetJob(req.Job.ID); err != nil {
+		log.Printf("[Worker %d] Skipping job %s: %v", id, req.Job.ID, err)
+		return
+	}
+
+	log.Printf("[Worker %d] Starting Job %s (%s)", id, req.Job.ID, req.Job.Type)
+
+	if err := d.store.UpdateJobStatus(req.Job.ID, models.JobStatusProcessing); err != nil {
+		log.Printf("Failed to update status for job %s: %v", req.Job.ID, err)
+		return
+	}
+
+	summary, err := req.Handler(d.store, req.Job)
+
+	if err != nil {
+		log.Printf("[Worker %d] Job %s Failed: %v", id, req.Job.ID, err)
+		d.store.FailJob(req.Job.ID, err.Error())
+	} else {
+		log.Printf("[Worker %d] Job %s Completed", id, req.Job.ID)
+		d.store.CompleteJob(req.Job.ID, summary)
 	}
 }
@@ -0,0 +1,131 @@
+package worker
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+	"github.com/JulianDominic/GatheringTheBulk/internal/store"
+)
+
+// staleUploadAge is how long an upload no job has claimed (a CSV waiting for
+// its columns to be mapped) is kept before it is considered abandoned.
+const staleUploadAge = 24 * time.Hour
+
+// RecoverJobs requeues the jobs a previous process left PENDING or PROCESSING.
+// Imports resume where they stopped, since rows already written are skipped;
+// anything that cannot be resumed is failed and its upload removed. Uploads
+// that were abandoned before a job was created are swept away.
+func RecoverJobs(s store.Store, d *Dispatcher) error {
+	jobs, err := s.ListUnfinishedJobs()
+	if err != nil {
+		return fmt.Errorf("failed to list unfinished jobs: %w", err)
+	}
+
+	// Settle failures before queueing anything, so the workers do not start
+	// writing while jobs are still being marked
+	var resumed []JobRequest
+	for i := range jobs {
+		job := &jobs[i]
+		handler, reason := resumeHandler(s, job)
+		if handler == nil {
+			log.Printf("Job %s (%s) was interrupted: %s", job.ID, job.Type, reason)
+			s.FailJob(job.ID, reason)
+			if job.IsImport() {
+				s.DeletePreviewRows(job.ID)
+			}
+			removeUploads(job.ID)
+			continue
+		}
+
+		resumed = append(resumed, JobRequest{Job: job, Handler: handler})
+	}
+
+	sweepUploads(s, time.Now().Add(-staleUploadAge))
+
+	for _, req := range resumed {
+		log.Printf("Resuming interrupted job %s (%s)", req.Job.ID, req.Job.Type)
+		d.QueueJob(req)
+	}
+	return nil
+}
+
+// resumeHandler picks the task that continues an interrupted job, or returns
+// the reason it cannot be continued.
+func resumeHandler(s store.Store, job *models.Job) (func(store.Store, *models.Job) (string, error), string) {
+	switch job.Type {
+	case models.JobTypeCSVImport:
+		if uploadExists(job.ID, ".csv") {
+			return ImportCSVTask, ""
+		}
+	case models.JobTypeTextImport:
+		if uploadExists(job.ID, ".txt") {
+			return ImportTextTask, ""
+		}
+	case models.JobTypeValuation:
+		return RecordValueTask, ""
+	default:
+		return nil, "Interrupted by a server restart, please start it again"
+	}
+
+	// A confirmed preview has no upload left, only its staged rows
+	var summary importSummary
+	json.Unmarshal([]byte(job.ResultSummary), &summary)
+	if summary.Preview {
+		if rows, err := s.ListPreviewRows(job.ID); err == nil && len(rows) > 0 {
+			return CommitPreviewTask, ""
+		}
+	}
+	return nil, "Interrupted by a server restart and the uploaded file is gone, please import it again"
+}
+
+func uploadExists(jobID, ext string) bool {
+	_, err := os.Stat(fmt.Sprintf("uploads/%s%s", jobID, ext))
+	return err == nil
+}
+
+// removeUploads deletes any file uploaded for a job.
+func removeUploads(jobID string) {
+	for _, ext := range []string{".csv", ".txt", ".json"} {
+		filename := fmt.Sprintf("uploads/%s%s", jobID, ext)
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove %s: %v", filename, err)
+		}
+	}
+}
+
+// sweepUploads deletes the uploads last written before cutoff that no job
+// row owns.
+func sweepUploads(s store.Store, cutoff time.Time) {
+	entries, err := os.ReadDir("uploads")
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Failed to list uploads: %v", err)
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.ModTime().After(cutoff) {
+			continue
+		}
+		jobID := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if _, err := s.GetJob(jobID); !errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+
+		filename := filepath.Join("uploads", entry.Name())
+		log.Printf("Removing abandoned upload %s", filename)
+		if err := os.Remove(filename); err != nil {
+			log.Printf("Failed to remove %s: %v", filename, err)
+		}
+	}
+}
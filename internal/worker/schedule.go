@@ -0,0 +1,148 @@
+package worker
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+	"github.com/JulianDominic/GatheringTheBulk/internal/store"
+	"github.com/google/uuid"
+)
+
+// valuationLastRunSetting holds the date the daily valuation last succeeded.
+const valuationLastRunSetting = "valuation_last_run"
+
+// Scheduler queues the jobs that run by themselves: backups at the time set
+// in the backup settings, and the daily collection valuation. Settings are
+// read on every check, so changes apply without a restart.
+type Scheduler struct {
+	Store      store.Store
+	Dispatcher *Dispatcher
+	DB         *sql.DB
+	BackupDir  string
+}
+
+// Start checks for due jobs every minute until the process exits.
+func (sc *Scheduler) Start() {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			sc.check(time.Now())
+			<-ticker.C
+		}
+	}()
+}
+
+// check queues whatever is due, waiting out a restore in progress so jobs
+// are not created in the database being replaced.
+func (sc *Scheduler) check(now time.Time) {
+	sc.Dispatcher.paused.RLock()
+	defer sc.Dispatcher.paused.RUnlock()
+
+	sc.checkBackup(now)
+	sc.checkValuation(now)
+}
+
+// QueueBackup creates a BACKUP job and hands it to the workers.
+func (sc *Scheduler) QueueBackup() (*models.Job, error) {
+	return sc.queue(models.JobTypeBackup, BackupTask(sc.DB, sc.BackupDir))
+}
+
+func (sc *Scheduler) queue(jobType models.JobType, handler func(store.Store, *models.Job) (string, error)) (*models.Job, error) {
+	job := &models.Job{
+		ID:        uuid.New().String(),
+		Type:      jobType,
+		Status:    models.JobStatusPending,
+		CreatedAt: time.Now(),
+	}
+	if err := sc.Store.CreateJob(job); err != nil {
+		return nil, fmt.Errorf("failed to create %s job: %w", jobType, err)
+	}
+
+	sc.Dispatcher.QueueJob(JobRequest{Job: job, Handler: handler})
+	return job, nil
+}
+
+// checkValuation records the collection's value once a day. A run that
+// fails is queued again on the next check.
+func (sc *Scheduler) checkValuation(now time.Time) {
+	today := now.Format("2006-01-02")
+	if last, _ := sc.Store.GetSetting(valuationLastRunSetting); last == today {
+		return
+	}
+
+	// The date is only recorded once the job succeeds, so wait for one
+	// already queued
+	jobs, err := sc.Store.ListUnfinishedJobs()
+	if err != nil {
+		log.Printf("Failed to check for a running collection valuation: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		if job.Type == models.JobTypeValuation {
+			return
+		}
+	}
+
+	if _, err := sc.queue(models.JobTypeValuation, RecordValueTask); err != nil {
+		log.Printf("Failed to queue collection valuation: %v", err)
+	}
+}
+
+func (sc *Scheduler) checkBackup(now time.Time) {
+	schedule, _ := sc.Store.GetSetting(BackupScheduleSetting)
+	at, _ := sc.Store.GetSetting(BackupTimeSetting)
+	slot, ok := lastBackupSlot(now, schedule, at)
+	if !ok {
+		return
+	}
+
+	// A backup missed while the server was down is taken as soon as it is up
+	lastRun, _ := sc.Store.GetSetting(backupLastRunSetting)
+	if last, err := time.Parse(time.RFC3339, lastRun); err == nil && !last.Before(slot) {
+		return
+	}
+
+	if err := sc.Store.SetSetting(backupLastRunSetting, now.Format(time.RFC3339)); err != nil {
+		log.Printf("Failed to record scheduled backup: %v", err)
+		return
+	}
+	if _, err := sc.QueueBackup(); err != nil {
+		log.Printf("Failed to queue scheduled backup: %v", err)
+		return
+	}
+	log.Printf("Queued scheduled backup to %s", sc.BackupDir)
+}
+
+// MarkBackupScheduled records the schedule as having just run, so turning
+// it on does not immediately take a backup for a time already passed today.
+func MarkBackupScheduled(s store.Store) error {
+	if v, err := s.GetSetting(backupLastRunSetting); err != nil || v != "" {
+		return err
+	}
+	return s.SetSetting(backupLastRunSetting, time.Now().Format(time.RFC3339))
+}
+
+// lastBackupSlot returns the most recent scheduled backup time at or before
+// now. Weekly backups are taken on Sundays.
+func lastBackupSlot(now time.Time, schedule, at string) (time.Time, bool) {
+	if schedule != "daily" && schedule != "weekly" {
+		return time.Time{}, false
+	}
+
+	clock, err := time.Parse("15:04", at)
+	if err != nil {
+		clock, _ = time.Parse("15:04", DefaultBackupTime)
+	}
+	slot := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if slot.After(now) {
+		slot = slot.AddDate(0, 0, -1)
+	}
+	if schedule == "weekly" {
+		slot = slot.AddDate(0, 0, -int(slot.Weekday()))
+	}
+	return slot, true
+}
@@ -0,0 +1,116 @@
+package worker
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/database"
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+	"github.com/JulianDominic/GatheringTheBulk/internal/store"
+)
+
+// Backup settings, stored in system_settings.
+const (
+	BackupScheduleSetting  = "backup_schedule"  // "off", "daily" or "weekly"
+	BackupTimeSetting      = "backup_time"      // Local time of day, "15:04"
+	BackupRetentionSetting = "backup_retention" // Number of backups to keep
+	backupLastRunSetting   = "backup_last_run"  // When the scheduler last queued a backup
+)
+
+const (
+	DefaultBackupTime      = "03:00"
+	DefaultBackupRetention = 7
+)
+
+// Backup files are named by the time they were taken, so sorting them by
+// name sorts them by age.
+const (
+	backupPrefix = "gatheringthebulk-"
+	backupSuffix = ".db"
+)
+
+// BackupTask returns a task that snapshots db into dir and then deletes the
+// oldest backups there beyond the configured retention.
+func BackupTask(db *sql.DB, dir string) func(store.Store, *models.Job) (string, error) {
+	return func(s store.Store, job *models.Job) (string, error) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create backup directory: %w", err)
+		}
+
+		// Write under a temporary name so an interrupted backup is never
+		// mistaken for a good one
+		name := backupPrefix + time.Now().Format("20060102-150405") + backupSuffix
+		path := filepath.Join(dir, name)
+		partial := path + ".partial"
+		os.Remove(partial)
+		if err := database.Backup(db, partial); err != nil {
+			os.Remove(partial)
+			return "", err
+		}
+		if err := os.Rename(partial, path); err != nil {
+			os.Remove(partial)
+			return "", fmt.Errorf("failed to save backup: %w", err)
+		}
+
+		var size int64
+		if info, err := os.Stat(path); err == nil {
+			size = info.Size()
+		}
+		summary := fmt.Sprintf("Saved %s (%.1f MB)", name, float64(size)/(1<<20))
+
+		removed, err := pruneBackups(dir, backupRetention(s))
+		if err != nil {
+			log.Printf("Failed to remove old backups: %v", err)
+		}
+		if removed > 0 {
+			summary += fmt.Sprintf(", removed %d old backup(s)", removed)
+		}
+		return summary, nil
+	}
+}
+
+// backupRetention returns how many backups to keep.
+func backupRetention(s store.Store) int {
+	if v, err := s.GetSetting(BackupRetentionSetting); err == nil && v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return DefaultBackupRetention
+}
+
+// pruneBackups deletes all but the newest keep backups in dir. Other files
+// in the directory are left alone.
+func pruneBackups(dir string, keep int) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), backupPrefix) && strings.HasSuffix(e.Name(), backupSuffix) {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) <= keep {
+		return 0, nil
+	}
+
+	sort.Strings(names)
+	removed := 0
+	for _, name := range names[:len(names)-keep] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,90 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+	"github.com/JulianDominic/GatheringTheBulk/internal/scryfall"
+	"github.com/JulianDominic/GatheringTheBulk/internal/store"
+)
+
+// imageRequestDelay spaces out image downloads to go easy on Scryfall.
+const imageRequestDelay = 50 * time.Millisecond
+
+// ImagePath returns where a card's image is cached. Images are spread over
+// subdirectories by the first characters of the ID, so no directory holds
+// the whole card database.
+func ImagePath(dir, scryfallID string) string {
+	sub := scryfallID
+	if len(sub) > 2 {
+		sub = sub[:2]
+	}
+	return filepath.Join(dir, sub, scryfallID+".jpg")
+}
+
+// CacheImagesTask returns a task that downloads the image of every owned
+// card, or of every card if all is set, into dir through c. Images already cached are
+// skipped, so running it again only fetches what is new.
+func CacheImagesTask(c *scryfall.Client, dir string, all bool) func(store.Store, *models.Job) (string, error) {
+	return func(s store.Store, job *models.Job) (string, error) {
+		images, err := s.ListCardImages(!all)
+		if err != nil {
+			return "", fmt.Errorf("failed to list card images: %w", err)
+		}
+
+		var downloaded, cached, failed int
+		for i, img := range images {
+			if i%50 == 0 {
+				s.UpdateJobProgress(job.ID, i, len(images))
+			}
+
+			path := ImagePath(dir, img.ScryfallID)
+			if _, err := os.Stat(path); err == nil {
+				cached++
+				continue
+			}
+
+			if err := downloadImage(c, img.ImageURI, path); err != nil {
+				log.Printf("Failed to download image for %s: %v", img.ScryfallID, err)
+				failed++
+				continue
+			}
+			downloaded++
+			time.Sleep(imageRequestDelay)
+		}
+		s.UpdateJobProgress(job.ID, len(images), len(images))
+
+		summary := fmt.Sprintf("Downloaded %d images, %d already cached", downloaded, cached)
+		if failed > 0 {
+			summary += fmt.Sprintf(", %d failed", failed)
+		}
+		return summary, nil
+	}
+}
+
+// downloadImage saves an image under a temporary name first, so an
+// interrupted download never leaves a truncated image behind.
+func downloadImage(c *scryfall.Client, url, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	partial := path + ".partial"
+	f, err := os.Create(partial)
+	if err != nil {
+		return err
+	}
+	err = c.Download(url, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(partial)
+		return err
+	}
+	return os.Rename(partial, path)
+}
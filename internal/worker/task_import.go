@@ -2,8 +2,10 @@ package worker
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"runtime"
 	"strconv"
@@ -15,16 +17,58 @@ import (
 	"github.com/JulianDominic/GatheringTheBulk/internal/store"
 )
 
-// importResult holds the outcome of processing a single CSV row.
+// importRow is a single input record (CSV row or decklist line) after its
+// values have been mapped onto the import fields.
+type importRow struct {
+	Index int               // Position in the source, used to resume imports
+	Raw   map[string]string // Original data, shown in the review queue
+
+	ScryfallID string // Exact printing, if the source knows it
+	Name       string
+	Set        string
+	CN         string
+	Quantity   int
+	Condition  string
+	IsFoil     bool
+	Language   string
+	Location   string
+	Price      float64
+}
+
+// importResult holds the outcome of processing a single row.
 // It is sent from workers to the collector.
 type importResult struct {
+	Index         int
 	Success       bool
 	InventoryItem models.InventoryItem // Populated if Success is true
 
 	// Review Data (Populated if Success is false)
 	IssueType      string
-	RawRow         []string
+	RawData        map[string]string
 	ProposedValues map[string]interface{}
+	Candidates     []models.MatchCandidate // Closest card names, if the name did not match
+}
+
+// importSummary is the JSON result of an import job.
+type importSummary struct {
+	Success int    `json:"success"`
+	Review  int    `json:"review"`
+	Format  string `json:"format"`
+	Preview bool   `json:"preview,omitempty"` // Counts are staged, not yet written
+}
+
+func (sum importSummary) String() string {
+	b, _ := json.Marshal(sum)
+	return string(b)
+}
+
+// importOptions decodes the options an import job was queued with.
+func importOptions(job *models.Job) models.ImportOptions {
+	var opts models.ImportOptions
+	if job.Params != "" {
+		json.Unmarshal([]byte(job.Params), &opts)
+	}
+	return opts
 }
 
 // ImportCSVTask reads a CSV file and processes it using a concurrent worker pool.
@@ -38,19 +82,32 @@ func ImportCSVTask(s store.Store, job *models.Job) (string, error) {
 	defer os.Remove(filename) // Cleanup after processing
 
 	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1 // Exports from other tools are not always rectangular
 
 	// Read Header
-	header, err := reader.Read()
+	header, err := readCSVHeader(reader)
 	if err != nil {
 		return "", fmt.Errorf("failed to read csv header: %w", err)
 	}
 
-	// Map header columns to indices (ReadOnly for workers)
-	colMap := make(map[string]int)
-	for i, h := range header {
-		colMap[strings.ToLower(strings.TrimSpace(h))] = i
+	// Map fields to column indices, using the user's mapping if one was given
+	// and the detected format otherwise (ReadOnly for workers)
+	opts := importOptions(job)
+	done, err := committedRows(s, job, opts.Preview)
+	if err != nil {
+		return "", fmt.Errorf("failed to load import progress: %w", err)
 	}
 
+	var format *importFormat
+	var colMap map[string]int
+	if opts.Mapping != nil {
+		format = mappedFormat(opts.Profile, opts.Mapping)
+		colMap = format.columns(headerIndex(header))
+	} else {
+		format, colMap = detectFormat(header)
+	}
+	log.Printf("Import %s: using %s format", job.ID, format.Name)
+
 	// ---------------------------------------------------------
 	// CONCURRENCY PIPELINE SETUP
 	// ---------------------------------------------------------
@@ -61,14 +118,16 @@ func ImportCSVTask(s store.Store, job *models.Job) (string, error) {
 		numWorkers = 2
 	}
 	// Buffer channels slightly to smooth out bursts
-	rowChan := make(chan []string, numWorkers*2)
+	rowChan := make(chan indexedRow, numWorkers*2)
 	resultChan := make(chan importResult, numWorkers*2)
 
 	var wg sync.WaitGroup
 
+	fuzzy := newFuzzyMatcher(s)
+
 	// 2. Helper for safely extracting values (Closure captures colMap)
-	getVal := func(row []string, key string) string {
-		if idx, ok := colMap[key]; ok && idx < len(row) {
+	getVal := func(row []string, field string) string {
+		if idx, ok := colMap[field]; ok && idx < len(row) {
 			return strings.TrimSpace(row[idx])
 		}
 		return ""
@@ -77,75 +136,29 @@ func ImportCSVTask(s store.Store, job *models.Job) (string, error) {
 	// 3. Worker Function
 	worker := func() {
 		defer wg.Done()
-		for row := range rowChan {
-			// Extract fields
-			name := getVal(row, "name")
-			set := getVal(row, "set")
-			cn := getVal(row, "collector_number")
-			if cn == "" {
-				cn = getVal(row, "cn")
-			}
-
-			qtyStr := getVal(row, "quantity")
-			if qtyStr == "" {
-				qtyStr = getVal(row, "qty")
-			}
-			qty, _ := strconv.Atoi(qtyStr)
-			if qty < 1 {
-				qty = 1
-			}
-
-			condition := getVal(row, "condition")
-			if condition == "" {
-				condition = "NM"
-			}
-
-			isFoil := strings.ToLower(getVal(row, "foil")) == "true"
-			language := getVal(row, "language")
-			if language == "" {
-				language = "en"
+		for r := range rowChan {
+			row := r.Fields
+			qty, _ := strconv.Atoi(getVal(row, fieldQuantity))
+			price, _ := strconv.ParseFloat(strings.Trim(getVal(row, fieldPrice), "$€£ "), 64)
+			location := getVal(row, fieldLocation)
+			if location == "" {
+				location = opts.Location
 			}
 
-			// Proposed values for Review (if needed)
-			props := mapProp(qty, condition, isFoil, language)
-
-			// Logic
-			var scryfallID string
-			var matchErr error
-
-			// DB Read Operation (Safe for concurrent usage)
-			if set != "" && cn != "" {
-				scryfallID, matchErr = s.FindCardBySetCN(set, cn)
-			} else if name != "" {
-				scryfallID, matchErr = s.FindSmartCard(name, set)
-			} else {
-				matchErr = fmt.Errorf("missing name or set/cn")
-			}
-
-			res := importResult{
-				RawRow:         row,
-				ProposedValues: props,
-			}
-
-			if matchErr == nil && scryfallID != "" {
-				res.Success = true
-				res.InventoryItem = models.InventoryItem{
-					ScryfallID: scryfallID,
-					Quantity:   qty,
-					Condition:  condition,
-					IsFoil:     isFoil,
-					Language:   language,
-					Location:   "Imported",
-				}
-			} else {
-				res.Success = false
-				res.IssueType = "AMBIGUOUS"
-				if matchErr != nil && matchErr.Error() == "not found" {
-					res.IssueType = "NOT_FOUND"
-				}
-			}
-
-			resultChan <- res
+			resultChan <- matchRow(s, fuzzy, importRow{
+				Index:      r.Index,
+				Raw:        mapRow(header, row),
+				ScryfallID: getVal(row, fieldScryfall),
+				Name:       getVal(row, fieldName),
+				Set:        getVal(row, fieldSet),
+				CN:         getVal(row, fieldCN),
+				Quantity:   qty,
+				Condition:  getVal(row, fieldCondition),
+				IsFoil:     parseFoil(getVal(row, fieldFoil)),
+				Language:   getVal(row, fieldLanguage),
+				Location:   location,
+				Price:      price,
+			})
 		}
 	}
 
@@ -156,19 +169,20 @@ func ImportCSVTask(s store.Store, job *models.Job) (string, error) {
 		go worker()
 	}
 
-	// 5. Start Feeder (Reads file and pushes to workers)
+	// 5. Start Feeder (Reads file and pushes to workers). Rows are numbered
+	// in file order so a resumed import can skip the ones already written.
 	go func() {
 		defer close(rowChan)
-		for {
+		for index := 0; ; index++ {
 			row, err := reader.Read()
 			if err == io.EOF {
 				break
 			}
-			if err != nil {
+			if _, ok := done[index]; ok || err != nil {
 				// Skip malformed lines, maybe log them?
 				continue
 			}
-			rowChan <- row
+			rowChan <- indexedRow{Index: index, Fields: row}
 		}
 	}()
 
@@ -178,13 +192,146 @@ func ImportCSVTask(s store.Store, job *models.Job) (string, error) {
 		close(resultChan)
 	}()
 
-	// ---------------------------------------------------------
-	// COLLECTOR LOOP (Main Thread - Single Writer)
-	// ---------------------------------------------------------
+	successCount, reviewCount := collectResults(s, job, resultChan, opts.Preview, done)
+
+	summary := importSummary{Success: successCount, Review: reviewCount, Format: format.Name, Preview: opts.Preview}
+	return summary.String(), nil
+}
+
+// matchRow normalises a row's values and resolves it to a Scryfall ID.
+// It only reads from the store, so it is safe to call from many workers.
+// Names that match no card are retried against the closest known names.
+func matchRow(s store.Store, fuzzy *fuzzyMatcher, r importRow) importResult {
+	qty := r.Quantity
+	if qty < 1 {
+		qty = 1
+	}
+
+	condition := models.NormalizeCondition(r.Condition)
+	if condition == "" {
+		condition = "NM"
+	}
+
+	language := models.NormalizeLanguage(r.Language)
+	if language == "" {
+		language = "en"
+	}
+
+	location := r.Location
+	if location == "" {
+		location = "Imported"
+	}
+
+	item := models.InventoryItem{
+		Quantity:      qty,
+		Condition:     condition,
+		IsFoil:        r.IsFoil,
+		Language:      language,
+		Location:      location,
+		PurchasePrice: r.Price,
+	}
+
+	// Proposed values for Review (if needed)
+	props := mapProp(item)
+
+	// Logic
+	var scryfallID string
+	var matchErr error
+
+	// DB Read Operation (Safe for concurrent usage). Our own exports (and
+	// ManaBox) carry the exact printing, which beats any lookup.
+	if r.ScryfallID != "" {
+		if card, err := s.GetCardByScryfallID(r.ScryfallID); err == nil {
+			scryfallID = card.ScryfallID
+		}
+	}
+
+	switch {
+	case scryfallID != "":
+	case r.Set != "" && r.CN != "":
+		scryfallID, matchErr = s.FindCardBySetCN(r.Set, r.CN, language)
+		if matchErr != nil && r.Name != "" {
+			// Collector numbers differ between tools (e.g. promo suffixes), so
+			// fall back to the name within the same set.
+			scryfallID, matchErr = s.FindSmartCard(r.Name, r.Set, language)
+		}
+	case r.Name != "":
+		scryfallID, matchErr = s.FindSmartCard(r.Name, r.Set, language)
+	default:
+		matchErr = fmt.Errorf("missing name, set/cn or scryfall id")
+	}
+
+	var candidates []models.MatchCandidate
+	if matchErr != nil && matchErr.Error() == "not found" && r.Name != "" {
+		candidates = fuzzy.candidates(r.Name)
+		if name, ok := fuzzy.accept(candidates); ok {
+			scryfallID, matchErr = s.FindSmartCard(name, r.Set, language)
+		}
+	}
+
+	res := importResult{
+		Index:          r.Index,
+		RawData:        r.Raw,
+		ProposedValues: props,
+	}
+
+	if matchErr == nil && scryfallID != "" {
+		res.Success = true
+		res.InventoryItem = item
+		res.InventoryItem.ScryfallID = scryfallID
+	} else {
+		res.Success = false
+		res.Candidates = candidates
+		res.IssueType = "AMBIGUOUS"
+		if matchErr != nil && matchErr.Error() == "not found" {
+			res.IssueType = "NOT_FOUND"
+		}
+	}
+
+	return res
+}
 
-	successCount := 0
-	reviewCount := 0
-	totalProcessed := 0
+// indexedRow is a raw CSV record with its position in the file.
+type indexedRow struct {
+	Index  int
+	Fields []string
+}
+
+// committedRows returns the rows an earlier run of this job already wrote,
+// keyed by row index, with true for rows that were added to inventory (or
+// matched, for a preview) and false for rows sent to review.
+func committedRows(s store.Store, job *models.Job, preview bool) (map[int]bool, error) {
+	if !preview {
+		return s.JobCommittedRows(job.ID)
+	}
+
+	rows, err := s.ListPreviewRows(job.ID)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[int]bool)
+	for _, row := range rows {
+		if row.RowIndex >= 0 {
+			done[row.RowIndex] = row.Status == "MATCHED"
+		}
+	}
+	return done, nil
+}
+
+// collectResults is the single writer of an import: it drains the result
+// channel into inventory and the review queue while reporting progress.
+// In preview mode every result is staged in import_preview instead. Rows in
+// done were written by an earlier run and count towards the totals.
+func collectResults(s store.Store, job *models.Job, resultChan <-chan importResult, preview bool, done map[int]bool) (successCount, reviewCount int) {
+	for _, added := range done {
+		if added {
+			successCount++
+		} else {
+			reviewCount++
+		}
+	}
+
+	totalProcessed := len(done)
 	lastUpdate := time.Now()
 
 	for res := range resultChan {
@@ -196,18 +343,30 @@ func ImportCSVTask(s store.Store, job *models.Job) (string, error) {
 			lastUpdate = time.Now()
 		}
 
+		if preview {
+			status := res.IssueType
+			if res.Success {
+				status = "MATCHED"
+				successCount++
+			} else {
+				reviewCount++
+			}
+			s.AddPreviewRow(job.ID, res.Index, status, res.InventoryItem.ScryfallID, res.RawData, res.ProposedValues, res.Candidates)
+			continue
+		}
+
 		if res.Success {
 			// Write Operation
-			if err := s.AddInventory(res.InventoryItem); err != nil {
+			if err := s.AddJobInventory(job.ID, res.Index, res.InventoryItem); err != nil {
 				// DB Write Error -> Send to Review
-				s.AddReviewItem(job.ID, "DB_ERROR", mapRow(header, res.RawRow), res.ProposedValues)
+				s.AddReviewItem(job.ID, res.Index, "DB_ERROR", res.RawData, res.ProposedValues, nil)
 				reviewCount++
 			} else {
 				successCount++
 			}
 		} else {
 			// Write Operation
-			s.AddReviewItem(job.ID, res.IssueType, mapRow(header, res.RawRow), res.ProposedValues)
+			s.AddReviewItem(job.ID, res.Index, res.IssueType, res.RawData, res.ProposedValues, res.Candidates)
 			reviewCount++
 		}
 	}
@@ -215,8 +374,7 @@ func ImportCSVTask(s store.Store, job *models.Job) (string, error) {
 	// Final progress update
 	s.UpdateJobProgress(job.ID, totalProcessed, totalProcessed)
 
-	summary := fmt.Sprintf(`{"success": %d, "review": %d}`, successCount, reviewCount)
-	return summary, nil
+	return successCount, reviewCount
 }
 
 func mapRow(header, row []string) map[string]string {
@@ -229,11 +387,13 @@ func mapRow(header, row []string) map[string]string {
 	return m
 }
 
-func mapProp(qty int, cond string, foil bool, lang string) map[string]interface{} {
+func mapProp(item models.InventoryItem) map[string]interface{} {
 	return map[string]interface{}{
-		"quantity":  qty,
-		"condition": cond,
-		"is_foil":   foil,
-		"language":  lang,
+		"quantity":       item.Quantity,
+		"condition":      item.Condition,
+		"is_foil":        item.IsFoil,
+		"language":       item.Language,
+		"location":       item.Location,
+		"purchase_price": item.PurchasePrice,
 	}
 }
@@ -0,0 +1,179 @@
+package worker
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+	"github.com/JulianDominic/GatheringTheBulk/internal/store"
+)
+
+// deckLineRe matches Arena/Moxfield style entries such as
+//
+//	4 Lightning Bolt
+//	4x Lightning Bolt (M11) 149
+//	1 Lightning Bolt (2XM) 129 *F*
+var deckLineRe = regexp.MustCompile(`^(?:(\d+)x?\s+)?(.+?)(?:\s+\(([A-Za-z0-9]+)\)(?:\s+([^\s*]+))?)?(?:\s+\*([A-Za-z]+)\*)?$`)
+
+// deckSections are the headers Arena, Moxfield and MTGO write between boards.
+var deckSections = map[string]string{
+	"deck":       "main",
+	"main":       "main",
+	"mainboard":  "main",
+	"sideboard":  "sideboard",
+	"commander":  "commander",
+	"companion":  "companion",
+	"maybeboard": "maybeboard",
+}
+
+// ImportTextTask reads a pasted decklist and imports every entry it can parse.
+func ImportTextTask(s store.Store, job *models.Job) (string, error) {
+	filename := fmt.Sprintf("uploads/%s.txt", job.ID)
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to open decklist file: %w", err)
+	}
+	defer f.Close()
+	defer os.Remove(filename) // Cleanup after processing
+
+	opts := importOptions(job)
+	done, err := committedRows(s, job, opts.Preview)
+	if err != nil {
+		return "", fmt.Errorf("failed to load import progress: %w", err)
+	}
+
+	// Decklists are small, so lines are matched sequentially and fed straight
+	// to the shared collector.
+	fuzzy := newFuzzyMatcher(s)
+	resultChan := make(chan importResult, 50)
+	var scanErr error
+	go func() {
+		defer close(resultChan)
+
+		section := "main"
+		inAbout := false
+		scanner := bufio.NewScanner(f)
+		for index := 0; scanner.Scan(); index++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "//") || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			// Section headers ("Sideboard", "SIDEBOARD:") switch the board for
+			// the lines that follow; parseDeckLine handles MTGO's "SB:" lines.
+			key := strings.ToLower(strings.TrimSuffix(line, ":"))
+			if sec, ok := deckSections[key]; ok {
+				section = sec
+				continue
+			}
+			if key == "about" {
+				inAbout = true
+				continue
+			}
+			if inAbout && strings.HasPrefix(key, "name ") {
+				inAbout = false
+				continue // Arena deck metadata
+			}
+
+			if _, ok := done[index]; ok {
+				continue // Written before the import was interrupted
+			}
+			row, ok := parseDeckLine(line)
+			if !ok {
+				// Keep the line so it can be fixed by hand in the review queue
+				resultChan <- unparsedLine(index, line, section, opts.Location)
+				continue
+			}
+			row.Index = index
+			row.Location = opts.Location
+			if row.Raw["section"] == "" {
+				row.Raw["section"] = section
+			}
+			resultChan <- matchRow(s, fuzzy, row)
+		}
+		scanErr = scanner.Err()
+	}()
+
+	successCount, reviewCount := collectResults(s, job, resultChan, opts.Preview, done)
+	if scanErr != nil {
+		return "", fmt.Errorf("failed to read decklist: %w", scanErr)
+	}
+
+	summary := importSummary{Success: successCount, Review: reviewCount, Format: "Text List", Preview: opts.Preview}
+	return summary.String(), nil
+}
+
+// unparsedLine is the review result for a decklist line parseDeckLine could
+// not read, proposing a single near-mint English copy.
+func unparsedLine(index int, line, section, location string) importResult {
+	if location == "" {
+		location = "Imported"
+	}
+	return importResult{
+		Index:     index,
+		IssueType: "NOT_FOUND",
+		RawData:   map[string]string{"line": line, "section": section},
+		ProposedValues: mapProp(models.InventoryItem{
+			Quantity:  1,
+			Condition: "NM",
+			Language:  "en",
+			Location:  location,
+		}),
+	}
+}
+
+// parseDeckLine splits a single decklist entry into its import fields. An
+// MTGO "SB:" prefix puts the entry in the sideboard. Lines that cannot be a
+// card, such as dividers, titles or stray brackets, are rejected.
+func parseDeckLine(line string) (importRow, bool) {
+	var section string
+	if strings.HasPrefix(strings.ToUpper(line), "SB:") {
+		line = strings.TrimSpace(line[3:])
+		section = "sideboard"
+	}
+
+	m := deckLineRe.FindStringSubmatch(line)
+	if m == nil || !plausibleName(m[2], m[1] != "") {
+		return importRow{}, false
+	}
+
+	qty := 1
+	if m[1] != "" {
+		qty, _ = strconv.Atoi(m[1])
+	}
+
+	row := importRow{
+		Name:     strings.TrimSpace(m[2]),
+		Set:      m[3],
+		CN:       m[4],
+		Quantity: qty,
+		// *F* marks foils and *E* etched foils in Moxfield exports
+		IsFoil: m[5] != "" && strings.ContainsAny(strings.ToUpper(m[5]), "FE"),
+	}
+	row.Raw = map[string]string{
+		"line":     line,
+		"name":     row.Name,
+		"set":      row.Set,
+		"cn":       row.CN,
+		"quantity": strconv.Itoa(qty),
+	}
+	if section != "" {
+		row.Raw["section"] = section
+	}
+	return row, true
+}
+
+// plausibleName reports whether text parsed from a decklist line could be a
+// card name. Names have letters and no brackets; a colon without a count in
+// front marks a label such as "Deck: My List" rather than a card.
+func plausibleName(name string, counted bool) bool {
+	if !strings.ContainsFunc(name, unicode.IsLetter) || strings.ContainsAny(name, "()[]") {
+		return false
+	}
+	return counted || !strings.Contains(name, ":")
+}
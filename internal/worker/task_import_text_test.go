@@ -0,0 +1,45 @@
+package worker
+
+import "testing"
+
+func TestParseDeckLine(t *testing.T) {
+	for _, tc := range []struct {
+		line    string
+		ok      bool
+		name    string
+		set, cn string
+		qty     int
+		foil    bool
+		section string
+	}{
+		{line: "4 Lightning Bolt (M11) 149 *F*", ok: true, name: "Lightning Bolt", set: "M11", cn: "149", qty: 4, foil: true},
+		{line: "1 Lightning Bolt (2XM) 129 *E*", ok: true, name: "Lightning Bolt", set: "2XM", cn: "129", qty: 1, foil: true},
+		{line: "SB: 1 Pyroblast", ok: true, name: "Pyroblast", qty: 1, section: "sideboard"},
+		{line: "4x Bolt", ok: true, name: "Bolt", qty: 4},
+		{line: "2 Fire // Ice (MH2) 290", ok: true, name: "Fire // Ice", set: "MH2", cn: "290", qty: 2},
+		{line: "1 Circle of Protection: Red", ok: true, name: "Circle of Protection: Red", qty: 1},
+		{line: "Lightning Bolt", ok: true, name: "Lightning Bolt", qty: 1},
+		{line: "Blitzschlag (M11)", ok: true, name: "Blitzschlag", set: "M11", qty: 1},
+		{line: "-----"},
+		{line: "12345"},
+		{line: "Deck: My List v2"},
+		{line: "3 Lightning Bolt (M11"},
+		{line: "1 [Promo] Sol Ring"},
+	} {
+		row, ok := parseDeckLine(tc.line)
+		if ok != tc.ok {
+			t.Errorf("parseDeckLine(%q) ok = %v, want %v", tc.line, ok, tc.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if row.Name != tc.name || row.Set != tc.set || row.CN != tc.cn || row.Quantity != tc.qty || row.IsFoil != tc.foil {
+			t.Errorf("parseDeckLine(%q) = %q (%s) #%s x%d foil=%v, want %q (%s) #%s x%d foil=%v", tc.line,
+				row.Name, row.Set, row.CN, row.Quantity, row.IsFoil, tc.name, tc.set, tc.cn, tc.qty, tc.foil)
+		}
+		if row.Raw["section"] != tc.section {
+			t.Errorf("parseDeckLine(%q) section = %q, want %q", tc.line, row.Raw["section"], tc.section)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+	"github.com/JulianDominic/GatheringTheBulk/internal/store"
+)
+
+// CommitPreviewTask writes the staged rows of a confirmed dry-run import to
+// inventory and the review queue, exactly as a normal import would have.
+func CommitPreviewTask(s store.Store, job *models.Job) (string, error) {
+	rows, err := s.ListPreviewRows(job.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load preview: %w", err)
+	}
+
+	var previous importSummary
+	json.Unmarshal([]byte(job.ResultSummary), &previous)
+
+	done, err := s.JobCommittedRows(job.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load import progress: %w", err)
+	}
+
+	resultChan := make(chan importResult, 50)
+	go func() {
+		defer close(resultChan)
+		for _, row := range rows {
+			if _, ok := done[row.RowIndex]; ok {
+				continue // Committed before the server restarted
+			}
+			resultChan <- previewResult(row)
+		}
+	}()
+
+	successCount, reviewCount := collectResults(s, job, resultChan, false, done)
+
+	if err := s.DeletePreviewRows(job.ID); err != nil {
+		return "", fmt.Errorf("failed to clear preview: %w", err)
+	}
+
+	summary := importSummary{Success: successCount, Review: reviewCount, Format: previous.Format}
+	return summary.String(), nil
+}
+
+// previewResult rebuilds the importResult a staged row was created from.
+func previewResult(row models.PreviewRow) importResult {
+	res := importResult{
+		Index:     row.RowIndex,
+		Success:   row.Status == "MATCHED",
+		IssueType: row.Status,
+	}
+	json.Unmarshal([]byte(row.RawData), &res.RawData)
+	json.Unmarshal([]byte(row.ProposedValues), &res.ProposedValues)
+	if row.Candidates != "" {
+		json.Unmarshal([]byte(row.Candidates), &res.Candidates)
+	}
+
+	if res.Success {
+		qty, _ := res.ProposedValues["quantity"].(float64)
+		condition, _ := res.ProposedValues["condition"].(string)
+		isFoil, _ := res.ProposedValues["is_foil"].(bool)
+		language, _ := res.ProposedValues["language"].(string)
+		location, _ := res.ProposedValues["location"].(string)
+		price, _ := res.ProposedValues["purchase_price"].(float64)
+
+		res.IssueType = ""
+		res.InventoryItem = models.InventoryItem{
+			ScryfallID:    row.ScryfallID,
+			Quantity:      int(qty),
+			Condition:     condition,
+			IsFoil:        isFoil,
+			Language:      language,
+			Location:      location,
+			PurchasePrice: price,
+		}
+	}
+	return res
+}
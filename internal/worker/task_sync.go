@@ -1,9 +1,17 @@
 package worker
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/JulianDominic/GatheringTheBulk/internal/models"
@@ -11,30 +19,212 @@ import (
 	"github.com/JulianDominic/GatheringTheBulk/internal/store"
 )
 
-// SyncDatabaseTask downloads and ingests Scryfall data.
-func SyncDatabaseTask(s store.Store, job *models.Job) (string, error) {
-	log.Println("Starting Scryfall Sync...")
+// Settings identifying the last bulk file ingested, so an unchanged file is
+// not downloaded again.
+const (
+	bulkUpdatedAtSetting = "scryfall_bulk_updated_at"
+	bulkSizeSetting      = "scryfall_bulk_size"
+)
+
+// BulkTypeSetting is the system setting naming the Scryfall bulk file to
+// sync, scryfall.DefaultCards unless set to scryfall.AllCards.
+const BulkTypeSetting = "scryfall_bulk_type"
+
+// BulkType returns the bulk file the sync downloads.
+func BulkType(s store.Store) string {
+	if v, _ := s.GetSetting(BulkTypeSetting); v == scryfall.AllCards {
+		return v
+	}
+	return scryfall.DefaultCards
+}
+
+// SaveBulkType changes the bulk file to sync. The last file ingested is
+// forgotten, so the next sync downloads the new one even if unchanged.
+func SaveBulkType(s store.Store, bulkType string) error {
+	if bulkType != scryfall.DefaultCards && bulkType != scryfall.AllCards {
+		return fmt.Errorf("unknown bulk data type %q", bulkType)
+	}
+	if bulkType == BulkType(s) {
+		return nil
+	}
+	if err := s.SetSetting(BulkTypeSetting, bulkType); err != nil {
+		return err
+	}
+	return s.SetSetting(bulkUpdatedAtSetting, "")
+}
+
+// SyncDatabaseTask returns a task that downloads and ingests Scryfall data
+// through c, then applies Scryfall's card migrations. The download is
+// skipped if the bulk file is the one already ingested, unless the job's
+// SyncOptions force it. If the options name a local file, that is ingested
+// instead.
+func SyncDatabaseTask(c *scryfall.Client) func(store.Store, *models.Job) (string, error) {
+	return func(s store.Store, job *models.Job) (string, error) {
+		var opts models.SyncOptions
+		if job.Params != "" {
+			json.Unmarshal([]byte(job.Params), &opts)
+		}
+		if opts.Path != "" {
+			return syncFromFile(s, job, opts)
+		}
+
+		bulkType := BulkType(s)
+		log.Printf("Starting Scryfall Sync (%s)...", bulkType)
+
+		bulk, err := c.FetchBulkData(bulkType)
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch download URL: %w", err)
+		}
+
+		size := strconv.FormatInt(bulk.Size, 10)
+		if !opts.Force {
+			lastUpdated, _ := s.GetSetting(bulkUpdatedAtSetting)
+			lastSize, _ := s.GetSetting(bulkSizeSetting)
+			if lastUpdated != "" && lastUpdated == bulk.UpdatedAt && lastSize == size {
+				log.Printf("Scryfall bulk file unchanged since %s, skipping download", bulk.UpdatedAt)
+				summary := fmt.Sprintf("Already up to date (Scryfall data from %s)", bulkDate(bulk.UpdatedAt))
+				return summary + migrationSummary(applyMigrations(s, c, job)), nil
+			}
+		}
+
+		// The set list is a nicety; cards are still worth syncing without it
+		if err := syncSets(s, c); err != nil {
+			log.Printf("Warning: failed to sync sets: %v", err)
+		}
+
+		stream, err := c.StreamBulkData(bulk.DownloadURI)
+		if err != nil {
+			return "", fmt.Errorf("failed to open stream: %w", err)
+		}
+		defer stream.Close()
+
+		count, err := ingestBulkData(s, job, stream)
+		if err != nil {
+			return "", err
+		}
+
+		summary := fmt.Sprintf("Successfully synced %d cards", count) + migrationSummary(applyMigrations(s, c, job))
+		finishSync(s, bulk.UpdatedAt, size)
+
+		return summary, nil
+	}
+}
+
+// applyMigrations follows Scryfall's card migrations: inventory of a merged
+// printing moves to the one it was merged into, and printings Scryfall
+// deleted are dropped. Any inventory left pointing at a printing that is not
+// in the database is then sent to the review queue. It returns how many
+// inventory rows were moved and how many were flagged.
+func applyMigrations(s store.Store, c *scryfall.Client, job *models.Job) (moved, flagged int) {
+	migrations, err := c.FetchMigrations()
+	if err != nil {
+		// Dangling rows are still worth flagging without the migrations
+		log.Printf("Warning: %v", err)
+	}
+
+	for _, m := range migrations {
+		var n int
+		switch m.MigrationStrategy {
+		case "merge":
+			n, err = s.MergeCard(m.OldScryfallID, m.NewScryfallID)
+			moved += n
+		case "delete":
+			n, err = s.RemoveCard(job.ID, m.OldScryfallID)
+			flagged += n
+		}
+		if err != nil {
+			log.Printf("Warning: failed to apply migration of %s: %v", m.OldScryfallID, err)
+		}
+	}
+
+	flagged += flagOrphans(s, job)
+
+	if moved > 0 || flagged > 0 {
+		log.Printf("Applied Scryfall migrations: moved %d inventory rows, flagged %d for review", moved, flagged)
+	}
+	return moved, flagged
+}
+
+// flagOrphans sends inventory whose printing is not in the database to the
+// review queue, returning how many rows were flagged.
+func flagOrphans(s store.Store, job *models.Job) int {
+	n, err := s.FlagOrphanedInventory(job.ID)
+	if err != nil {
+		log.Printf("Warning: failed to flag orphaned inventory: %v", err)
+	}
+	return n
+}
+
+// migrationSummary describes what applying migrations did, for appending to
+// a sync's summary.
+func migrationSummary(moved, flagged int) string {
+	var summary string
+	if moved > 0 {
+		summary += fmt.Sprintf(", moved %d stack(s) to merged printings", moved)
+	}
+	if flagged > 0 {
+		summary += fmt.Sprintf(", sent %d stack(s) of removed printings to review", flagged)
+	}
+	return summary
+}
+
+// syncFromFile ingests a bulk data file from disk, which may be gzipped.
+// Uploaded files are removed afterwards. The server may be offline, so
+// migrations are not fetched, but inventory left without a printing is
+// still flagged.
+func syncFromFile(s store.Store, job *models.Job, opts models.SyncOptions) (string, error) {
+	log.Printf("Starting Scryfall Sync from %s...", opts.Path)
+	if opts.Uploaded {
+		defer os.Remove(opts.Path)
+	}
 
-	url, err := scryfall.FetchBulkDataURL()
+	f, err := os.Open(opts.Path)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch download URL: %w", err)
+		return "", fmt.Errorf("failed to open bulk file: %w", err)
+	}
+	defer f.Close()
+
+	var src io.Reader = bufio.NewReader(f)
+	if magic, _ := src.(*bufio.Reader).Peek(2); bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			return "", fmt.Errorf("failed to read gzip bulk file: %w", err)
+		}
+		defer gz.Close()
+		src = gz
 	}
 
-	stream, err := scryfall.StreamBulkData(url)
+	count, err := ingestBulkData(s, job, src)
 	if err != nil {
-		return "", fmt.Errorf("failed to open stream: %w", err)
+		return "", err
+	}
+
+	source := filepath.Base(opts.Path)
+	if opts.Uploaded {
+		source = "the uploaded file"
 	}
-	defer stream.Close()
+	summary := fmt.Sprintf("Successfully synced %d cards from %s", count, source) + migrationSummary(0, flagOrphans(s, job))
 
-	dec := json.NewDecoder(stream)
+	// The file's age is unknown, so the next online sync must not be skipped
+	finishSync(s, "", "")
+
+	return summary, nil
+}
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ingestBulkData decodes a Scryfall bulk data JSON array and upserts its
+// cards in batches. Cards that fail to decode are logged and skipped.
+func ingestBulkData(s store.Store, job *models.Job, r io.Reader) (int, error) {
+	dec := json.NewDecoder(r)
 
 	// Consume opening bracket
 	t, err := dec.Token()
 	if err != nil {
-		return "", err
+		return 0, err
 	}
 	if delim, ok := t.(json.Delim); !ok || delim != '[' {
-		return "", fmt.Errorf("expected JSON array start")
+		return 0, fmt.Errorf("expected JSON array start")
 	}
 
 	count := 0
@@ -48,18 +238,12 @@ func SyncDatabaseTask(s store.Store, job *models.Job) (string, error) {
 			continue
 		}
 
-		cardBatch = append(cardBatch, models.Card{
-			ScryfallID:      sfCard.ID,
-			Name:            sfCard.Name,
-			SetCode:         sfCard.Set,
-			CollectorNumber: sfCard.CollectorNumber,
-			ImageURI:        sfCard.GetFrontImage(),
-		})
+		cardBatch = append(cardBatch, cardFromScryfall(&sfCard))
 
 		count++
 		if len(cardBatch) >= batchSize {
 			if err := s.BatchUpsertCards(cardBatch); err != nil {
-				return "", fmt.Errorf("batch commit failed: %w", err)
+				return count, fmt.Errorf("batch commit failed: %w", err)
 			}
 			cardBatch = nil
 			s.UpdateJobProgress(job.ID, count, 0)
@@ -69,17 +253,144 @@ func SyncDatabaseTask(s store.Store, job *models.Job) (string, error) {
 	// Final Batch
 	if len(cardBatch) > 0 {
 		if err := s.BatchUpsertCards(cardBatch); err != nil {
-			return "", fmt.Errorf("final commit failed: %w", err)
+			return count, fmt.Errorf("final commit failed: %w", err)
 		}
 	}
 
 	// Consume closing bracket
 	_, _ = dec.Token()
+	return count, nil
+}
 
+// finishSync records a completed sync and the bulk file it came from, then
+// revalues the collection at the new prices.
+func finishSync(s store.Store, bulkUpdatedAt, bulkSize string) {
 	ts := time.Now().Format("2006-01-02 15:04:05")
 	if err := s.SetSetting("scryfall_last_sync", ts); err != nil {
 		log.Printf("Warning: failed to update last sync setting: %v", err)
 	}
+	if err := s.SetSetting(bulkUpdatedAtSetting, bulkUpdatedAt); err != nil {
+		log.Printf("Warning: failed to record bulk file: %v", err)
+	}
+	if err := s.SetSetting(bulkSizeSetting, bulkSize); err != nil {
+		log.Printf("Warning: failed to record bulk file: %v", err)
+	}
+
+	// Prices have moved, so today's collection value is out of date
+	if _, err := recordCollectionValue(s); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// bulkDate formats a bulk file timestamp for display, falling back to the
+// raw value if Scryfall changes its format.
+func bulkDate(updatedAt string) string {
+	t, err := time.Parse(time.RFC3339, updatedAt)
+	if err != nil {
+		return updatedAt
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// syncSets refreshes the set list and downloads any set icons not cached yet.
+func syncSets(s store.Store, c *scryfall.Client) error {
+	sfSets, err := c.FetchSets()
+	if err != nil {
+		return err
+	}
 
-	return fmt.Sprintf("Successfully synced %d cards", count), nil
+	sets := make([]models.Set, len(sfSets))
+	for i, set := range sfSets {
+		sets[i] = models.Set{
+			Code:          set.Code,
+			Name:          set.Name,
+			SetType:       set.SetType,
+			ReleasedAt:    set.ReleasedAt,
+			CardCount:     set.CardCount,
+			ParentSetCode: set.ParentSetCode,
+			IconSVGURI:    set.IconSVGURI,
+		}
+	}
+	if err := s.UpsertSets(sets); err != nil {
+		return fmt.Errorf("failed to save sets: %w", err)
+	}
+
+	uris, err := s.ListMissingSetIcons()
+	if err != nil {
+		return fmt.Errorf("failed to list missing set icons: %w", err)
+	}
+	for _, uri := range uris {
+		svg, err := c.FetchIcon(uri)
+		if err != nil {
+			log.Printf("Failed to download set icon %s: %v", uri, err)
+			continue
+		}
+		if err := s.SaveSetIcon(uri, string(svg)); err != nil {
+			return fmt.Errorf("failed to save set icon: %w", err)
+		}
+		time.Sleep(iconRequestDelay)
+	}
+	log.Printf("Synced %d sets, downloaded %d icons", len(sets), len(uris))
+	return nil
+}
+
+// iconRequestDelay spaces out icon downloads, as Scryfall asks of clients.
+const iconRequestDelay = 100 * time.Millisecond
+
+// cardFromScryfall converts a bulk data entry to the stored card record.
+func cardFromScryfall(c *scryfall.Card) models.Card {
+	return models.Card{
+		ScryfallID:      c.ID,
+		OracleID:        c.GetOracleID(),
+		Name:            c.Name,
+		Lang:            c.Lang,
+		PrintedName:     c.GetPrintedName(),
+		ReleasedAt:      c.ReleasedAt,
+		SetCode:         c.Set,
+		SetName:         c.SetName,
+		Layout:          c.Layout,
+		MTGOID:          c.MTGOID,
+		CollectorNumber: c.CollectorNumber,
+		Rarity:          c.Rarity,
+		TypeLine:        c.GetTypeLine(),
+		ManaCost:        c.GetManaCost(),
+		CMC:             c.CMC,
+		Colors:          colorString(c.GetColors()),
+		ColorIdentity:   colorString(c.ColorIdentity),
+		Finishes:        strings.Join(c.Finishes, ","),
+		ImageURI:        c.GetFrontImage(),
+		Prices: models.CardPrices{
+			USD:       parsePrice(c.Prices.USD),
+			USDFoil:   parsePrice(c.Prices.USDFoil),
+			USDEtched: parsePrice(c.Prices.USDEtched),
+			EUR:       parsePrice(c.Prices.EUR),
+			EURFoil:   parsePrice(c.Prices.EURFoil),
+			Tix:       parsePrice(c.Prices.Tix),
+		},
+	}
+}
+
+func parsePrice(s *string) *float64 {
+	if s == nil {
+		return nil
+	}
+	v, err := strconv.ParseFloat(*s, 64)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+// colorString writes color letters in WUBRG order.
+func colorString(colors []string) string {
+	var b strings.Builder
+	for _, letter := range []string{"W", "U", "B", "R", "G"} {
+		for _, c := range colors {
+			if c == letter {
+				b.WriteString(letter)
+				break
+			}
+		}
+	}
+	return b.String()
 }
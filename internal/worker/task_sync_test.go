@@ -0,0 +1,238 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/database"
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+	"github.com/JulianDominic/GatheringTheBulk/internal/scryfall"
+	"github.com/JulianDominic/GatheringTheBulk/internal/store"
+)
+
+// newTestStore opens a fresh database in a temporary directory.
+func newTestStore(t *testing.T) store.Store {
+	t.Helper()
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(database.Close)
+	return store.NewSQLiteStore(database.DB)
+}
+
+// offlineClient returns a Scryfall client that fails the test if it is used.
+func offlineClient(t *testing.T) *scryfall.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to Scryfall: %s", r.URL)
+		http.Error(w, "offline", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+
+	c := scryfall.NewClient()
+	c.BaseURL = srv.URL
+	c.MaxRetries = 0
+	return c
+}
+
+// queueSync creates a SYNC_DB job with the given options, as the handlers do.
+func queueSync(t *testing.T, s store.Store, opts models.SyncOptions) *models.Job {
+	t.Helper()
+	params, _ := json.Marshal(opts)
+	job := &models.Job{
+		ID:        "sync-" + strings.ReplaceAll(t.Name(), "/", "-"),
+		Type:      models.JobTypeSyncDB,
+		Status:    models.JobStatusPending,
+		CreatedAt: time.Now(),
+		Params:    string(params),
+	}
+	if err := s.CreateJob(job); err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+	return job
+}
+
+func TestSyncFromFile(t *testing.T) {
+	for _, name := range []string{"bulk_cards.json", "bulk_cards.json.gz"} {
+		t.Run(name, func(t *testing.T) {
+			s := newTestStore(t)
+			s.SetSetting(bulkUpdatedAtSetting, "2026-01-01T10:00:00+00:00")
+			s.SetSetting(bulkSizeSetting, "123456")
+
+			job := queueSync(t, s, models.SyncOptions{Path: filepath.Join("testdata", name)})
+			summary, err := SyncDatabaseTask(offlineClient(t))(s, job)
+			if err != nil {
+				t.Fatalf("sync failed: %v", err)
+			}
+			if want := "Successfully synced 3 cards from " + name; summary != want {
+				t.Errorf("summary = %q, want %q", summary, want)
+			}
+
+			for id, want := range map[string]string{
+				"e3285e6b-3e79-4d7c-bf96-d920f973b122": "Lightning Bolt",
+				"0b6a8e4b-b4ba-4b62-b9b4-fd1b2b4b6a3c": "Fire // Ice",
+				"11bf83bb-c95b-4b4f-9a56-ce7a1816307a": "Delver of Secrets // Insectile Aberration",
+			} {
+				card, err := s.GetCardByScryfallID(id)
+				if err != nil {
+					t.Errorf("card %s not synced: %v", id, err)
+					continue
+				}
+				if card.Name != want {
+					t.Errorf("card %s name = %q, want %q", id, card.Name, want)
+				}
+			}
+			if id, err := s.FindSmartCard("火+氷", "mh2", "ja"); err != nil || id != "0b6a8e4b-b4ba-4b62-b9b4-fd1b2b4b6a3c" {
+				t.Errorf("printed name lookup = %q, %v", id, err)
+			}
+
+			var usd, usdFoil, tix float64
+			err = database.DB.QueryRow("SELECT usd, usd_foil, tix FROM card_prices WHERE scryfall_id = ?",
+				"e3285e6b-3e79-4d7c-bf96-d920f973b122").Scan(&usd, &usdFoil, &tix)
+			if err != nil {
+				t.Fatalf("reading prices: %v", err)
+			}
+			if usd != 1.5 || usdFoil != 7.25 || tix != 0.03 {
+				t.Errorf("prices = %v/%v/%v, want 1.5/7.25/0.03", usd, usdFoil, tix)
+			}
+			var unpriced int
+			database.DB.QueryRow("SELECT COUNT(*) FROM card_prices WHERE scryfall_id = ? AND usd IS NULL",
+				"0b6a8e4b-b4ba-4b62-b9b4-fd1b2b4b6a3c").Scan(&unpriced)
+			if unpriced != 1 {
+				t.Errorf("null prices were not stored as missing")
+			}
+
+			// The file's age is unknown, so the next online sync must download
+			for _, key := range []string{bulkUpdatedAtSetting, bulkSizeSetting} {
+				if v, _ := s.GetSetting(key); v != "" {
+					t.Errorf("%s = %q, want it reset", key, v)
+				}
+			}
+			if v, _ := s.GetSetting("scryfall_last_sync"); v == "" {
+				t.Error("last sync time was not recorded")
+			}
+			if _, err := os.Stat(filepath.Join("testdata", name)); err != nil {
+				t.Errorf("a file on the server was removed: %v", err)
+			}
+		})
+	}
+}
+
+func TestSyncFromUploadedFile(t *testing.T) {
+	s := newTestStore(t)
+
+	data, err := os.ReadFile(filepath.Join("testdata", "bulk_cards.json.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "upload.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	job := queueSync(t, s, models.SyncOptions{Path: path, Uploaded: true})
+	summary, err := SyncDatabaseTask(offlineClient(t))(s, job)
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if want := "Successfully synced 3 cards from the uploaded file"; summary != want {
+		t.Errorf("summary = %q, want %q", summary, want)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("upload was not removed: %v", err)
+	}
+}
+
+func TestSyncFromFileRejectsNonArray(t *testing.T) {
+	s := newTestStore(t)
+	path := filepath.Join(t.TempDir(), "object.json")
+	if err := os.WriteFile(path, []byte(`{"object":"list"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	job := queueSync(t, s, models.SyncOptions{Path: path})
+	if _, err := SyncDatabaseTask(offlineClient(t))(s, job); err == nil {
+		t.Fatal("sync of a non-array file succeeded")
+	}
+}
+
+func TestSyncFromFileFlagsOrphans(t *testing.T) {
+	s := newTestStore(t)
+	err := s.AddInventory(models.InventoryItem{ScryfallID: "never-synced", Quantity: 2, Condition: "NM", Language: "en", Location: "Binder"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := queueSync(t, s, models.SyncOptions{Path: filepath.Join("testdata", "bulk_cards.json")})
+	summary, err := SyncDatabaseTask(offlineClient(t))(s, job)
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if want := "Successfully synced 3 cards from bulk_cards.json, sent 1 stack(s) of removed printings to review"; summary != want {
+		t.Errorf("summary = %q, want %q", summary, want)
+	}
+	if n, _ := s.CountReviewItems(); n != 1 {
+		t.Errorf("%d review items, want 1", n)
+	}
+}
+
+func TestSyncUpToDateAppliesMigrations(t *testing.T) {
+	s := newTestStore(t)
+	usd := 1.0
+	err := s.BatchUpsertCards([]models.Card{
+		{ScryfallID: "old", Name: "Lightning Bolt", Lang: "en", SetCode: "m11", CollectorNumber: "149", Prices: models.CardPrices{USD: &usd}},
+		{ScryfallID: "new", Name: "Lightning Bolt", Lang: "en", SetCode: "m11", CollectorNumber: "146", Prices: models.CardPrices{USD: &usd}},
+		{ScryfallID: "gone", Name: "Brainstorm", Lang: "en", SetCode: "sta", CollectorNumber: "13", Prices: models.CardPrices{USD: &usd}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"old", "gone"} {
+		if err := s.AddInventory(models.InventoryItem{ScryfallID: id, Quantity: 1, Condition: "NM", Language: "en", Location: "Binder"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.SetSetting(bulkUpdatedAtSetting, "2026-10-01T09:00:00+00:00")
+	s.SetSetting(bulkSizeSetting, "512")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bulk-data":
+			fmt.Fprint(w, `{"data":[{"type":"default_cards","download_uri":"/never","updated_at":"2026-10-01T09:00:00+00:00","size":512}]}`)
+		case "/migrations":
+			fmt.Fprint(w, `{"data":[
+				{"old_scryfall_id":"old","new_scryfall_id":"new","migration_strategy":"merge"},
+				{"old_scryfall_id":"gone","migration_strategy":"delete"}
+			],"has_more":false}`)
+		default:
+			t.Errorf("unexpected request for %s while up to date", r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	c := scryfall.NewClient()
+	c.BaseURL = srv.URL
+	c.MaxRetries = 0
+
+	summary, err := SyncDatabaseTask(c)(s, queueSync(t, s, models.SyncOptions{}))
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if !strings.HasPrefix(summary, "Already up to date") ||
+		!strings.HasSuffix(summary, ", moved 1 stack(s) to merged printings, sent 1 stack(s) of removed printings to review") {
+		t.Errorf("summary = %q, want the migrations applied", summary)
+	}
+	if n, _ := s.CountReviewItems(); n != 1 {
+		t.Errorf("%d review items, want 1", n)
+	}
+	if _, err := s.GetCardByScryfallID("old"); err == nil {
+		t.Error("the merged printing is still in the database")
+	}
+}
@@ -0,0 +1,40 @@
+package worker
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/JulianDominic/GatheringTheBulk/internal/models"
+	"github.com/JulianDominic/GatheringTheBulk/internal/store"
+)
+
+// RecordValueTask records today's collection value at current prices and
+// marks the daily valuation as done.
+func RecordValueTask(s store.Store, job *models.Job) (string, error) {
+	p, err := recordCollectionValue(s)
+	if err != nil {
+		return "", err
+	}
+	if err := s.SetSetting(valuationLastRunSetting, p.Date); err != nil {
+		return "", fmt.Errorf("failed to record valuation date: %w", err)
+	}
+	return fmt.Sprintf("Collection worth $%.2f (%d cards)", p.USD, p.Cards), nil
+}
+
+// recordCollectionValue values the whole inventory and stores it as
+// today's value.
+func recordCollectionValue(s store.Store) (models.ValuePoint, error) {
+	p := models.ValuePoint{Date: time.Now().Format("2006-01-02")}
+
+	var err error
+	if p.USD, p.Unpriced, err = s.InventoryValue(""); err != nil {
+		return p, fmt.Errorf("failed to value inventory: %w", err)
+	}
+	if p.Cards, err = s.CollectionSize(); err != nil {
+		return p, fmt.Errorf("failed to count inventory: %w", err)
+	}
+	if err := s.RecordCollectionValue(p); err != nil {
+		return p, fmt.Errorf("failed to save collection value: %w", err)
+	}
+	return p, nil
+}